@@ -0,0 +1,71 @@
+package command
+
+import (
+	"errors"
+	"strings"
+)
+
+const prefix = "!"
+
+// Name is the name of a chat command teams can use within their match thread
+type Name string
+
+const (
+	Ready   Name = "ready"
+	Unready Name = "unready"
+	Pause   Name = "pause"
+	Help    Name = "help"
+	Admin   Name = "admin"
+)
+
+var (
+	ErrNoCommand       = errors.New("text is no command")
+	ErrUnknownCommand  = errors.New("unknown command")
+	ErrMissingArgument = errors.New("command requires an argument")
+)
+
+// Command is a chat command parsed from a forum reply
+type Command struct {
+	Name     Name   `json:"name"`
+	Argument string `json:"argument,omitempty"`
+}
+
+// withArgument lists the commands which require an argument, all other commands ignore trailing text
+var withArgument = map[Name]bool{
+	Ready:   false,
+	Unready: false,
+	Pause:   false,
+	Help:    false,
+	Admin:   true,
+}
+
+// Parse parses the command at the start of the text. Command names are case-insensitive, the argument is the remaining
+// text of the reply with collapsed whitespace. ErrNoCommand is returned if the line does not start
+// with the command prefix.
+func Parse(text string) (*Command, error) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, prefix) {
+		return nil, ErrNoCommand
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(text, prefix))
+	if len(fields) == 0 {
+		return nil, ErrNoCommand
+	}
+
+	name := Name(strings.ToLower(fields[0]))
+	requiresArgument, ok := withArgument[name]
+	if !ok {
+		return nil, ErrUnknownCommand
+	}
+
+	cmd := Command{Name: name}
+	if requiresArgument {
+		cmd.Argument = strings.Join(fields[1:], " ")
+		if cmd.Argument == "" {
+			return nil, ErrMissingArgument
+		}
+	}
+
+	return &cmd, nil
+}
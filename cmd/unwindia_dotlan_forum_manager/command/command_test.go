@@ -0,0 +1,68 @@
+package command
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    *Command
+		wantErr error
+	}{
+		{
+			name: "ok-ready",
+			text: "!ready",
+			want: &Command{Name: Ready},
+		},
+		{
+			name: "ok-case_and_whitespace",
+			text: "\n  !UnReady  \n",
+			want: &Command{Name: Unready},
+		},
+		{
+			name: "ok-trailing_text_ignored",
+			text: "!pause need a minute",
+			want: &Command{Name: Pause},
+		},
+		{
+			name: "ok-admin",
+			text: "!admin our server\ncrashed  again",
+			want: &Command{Name: Admin, Argument: "our server crashed again"},
+		},
+		{
+			name:    "err-no_command",
+			text:    "gl hf !ready",
+			wantErr: ErrNoCommand,
+		},
+		{
+			name:    "err-only_prefix",
+			text:    "! ",
+			wantErr: ErrNoCommand,
+		},
+		{
+			name:    "err-unknown",
+			text:    "!surrender",
+			wantErr: ErrUnknownCommand,
+		},
+		{
+			name:    "err-admin_without_text",
+			text:    "!admin",
+			wantErr: ErrMissingArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.text)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	unwindiaConfig "github.com/GSH-LAN/Unwindia_common/src/go/config"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/routing"
+)
+
+// Config is the Unwindia config extended by the settings of the forum manager
+type Config struct {
+	unwindiaConfig.Config
+	ForumRouting routing.Table `json:"forumRouting,omitempty"`
+}
+
+type ConfigClient interface {
+	GetConfig() *Config
+}
+
+// unwindiaConfigClient provides the config of an Unwindia config client, which has no settings of the forum manager
+type unwindiaConfigClient struct {
+	client unwindiaConfig.ConfigClient
+}
+
+// FromUnwindiaConfigClient returns a ConfigClient for the given Unwindia config client. The config has no forum routing.
+func FromUnwindiaConfigClient(client unwindiaConfig.ConfigClient) ConfigClient {
+	return &unwindiaConfigClient{client: client}
+}
+
+func (c *unwindiaConfigClient) GetConfig() *Config {
+	return &Config{Config: *c.client.GetConfig()}
+}
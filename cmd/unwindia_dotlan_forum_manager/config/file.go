@@ -0,0 +1,85 @@
+package config
+
+import (
+	"context"
+	unwindiaConfig "github.com/GSH-LAN/Unwindia_common/src/go/config"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/routing"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+	"os"
+	"sync"
+)
+
+// ConfigFileImpl extends the Unwindia config file client by the forum routing. Loading and watching the file is left to
+// the Unwindia client, only the forumRouting key is decoded from the same file whenever the Unwindia client reloaded it.
+type ConfigFileImpl struct {
+	client         unwindiaConfig.ConfigClient
+	configFilename string
+	lock           sync.Mutex
+	loadedConfig   *unwindiaConfig.Config
+	currentConfig  *Config
+}
+
+func NewConfigFile(ctx context.Context, filename, templatesDirectory string) (ConfigClient, error) {
+	client, err := unwindiaConfig.NewConfigFile(ctx, filename, templatesDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	return newConfigFile(client, filename)
+}
+
+func newConfigFile(client unwindiaConfig.ConfigClient, filename string) (*ConfigFileImpl, error) {
+	forumRouting, err := readForumRouting(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	loadedConfig := client.GetConfig()
+
+	return &ConfigFileImpl{
+		client:         client,
+		configFilename: filename,
+		loadedConfig:   loadedConfig,
+		currentConfig:  &Config{Config: *loadedConfig, ForumRouting: forumRouting},
+	}, nil
+}
+
+// GetConfig returns the config of the Unwindia client together with the forum routing. The Unwindia client replaces its
+// config on every reload of the file, which triggers reading the forum routing again.
+func (c *ConfigFileImpl) GetConfig() *Config {
+	loadedConfig := c.client.GetConfig()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if loadedConfig != c.loadedConfig {
+		forumRouting, err := readForumRouting(c.configFilename)
+		if err != nil {
+			log.Error().Err(err).Str("filename", c.configFilename).Msg("Error reading forum routing, keeping last known routing")
+			forumRouting = c.currentConfig.ForumRouting
+		}
+
+		c.loadedConfig = loadedConfig
+		c.currentConfig = &Config{Config: *loadedConfig, ForumRouting: forumRouting}
+	}
+
+	return c.currentConfig
+}
+
+// readForumRouting decodes the forumRouting key of the config file
+func readForumRouting(filename string) (routing.Table, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg struct {
+		ForumRouting routing.Table `json:"forumRouting"`
+	}
+	if err = jsoniter.Unmarshal(content, &cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg.ForumRouting, nil
+}
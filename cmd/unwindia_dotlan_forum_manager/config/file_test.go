@@ -0,0 +1,89 @@
+package config
+
+import (
+	unwindiaConfig "github.com/GSH-LAN/Unwindia_common/src/go/config"
+	"os"
+	"path"
+	"testing"
+)
+
+// testUnwindiaConfigClient stands in for the Unwindia config file client, a reload is simulated by replacing the config
+type testUnwindiaConfigClient struct {
+	config *unwindiaConfig.Config
+}
+
+func (t *testUnwindiaConfigClient) GetConfig() *unwindiaConfig.Config {
+	return t.config
+}
+
+func writeTestFile(t *testing.T, filename, content string) {
+	t.Helper()
+	if err := os.WriteFile(filename, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigFileImpl_GetConfig(t *testing.T) {
+	filename := path.Join(t.TempDir(), "config.json")
+	writeTestFile(t, filename, `{"cmsConfig": {"dotlanUserId": 3}, "forumRouting": [{"game": "csgo", "forumId": 10}]}`)
+
+	client := &testUnwindiaConfigClient{config: &unwindiaConfig.Config{CmsConfig: unwindiaConfig.CmsConfig{UserId: 3}}}
+	c, err := newConfigFile(client, filename)
+	if err != nil {
+		t.Fatalf("newConfigFile() error = %v", err)
+	}
+
+	cfg := c.GetConfig()
+	if cfg.CmsConfig.UserId != 3 {
+		t.Errorf("GetConfig() cmsConfig = %+v, want userId 3", cfg.CmsConfig)
+	}
+	if got, ok := cfg.ForumRouting.ForumID("csgo", ""); got != 10 || !ok {
+		t.Errorf("ForumRouting.ForumID() = %v, %v, want 10, true", got, ok)
+	}
+
+	// the file is not read again as long as the Unwindia client did not reload it
+	writeTestFile(t, filename, `{"forumRouting": [{"game": "csgo", "forumId": 11}]}`)
+	if got, _ := c.GetConfig().ForumRouting.ForumID("csgo", ""); got != 10 {
+		t.Errorf("ForumRouting.ForumID() without reload = %v, want 10", got)
+	}
+
+	// a reload of the Unwindia client applies the modified routing
+	client.config = &unwindiaConfig.Config{CmsConfig: unwindiaConfig.CmsConfig{UserId: 4}}
+	cfg = c.GetConfig()
+	if got, ok := cfg.ForumRouting.ForumID("csgo", ""); got != 11 || !ok || cfg.CmsConfig.UserId != 4 {
+		t.Errorf("GetConfig() after reload = %v, %v, userId %v, want 11, true, userId 4", got, ok, cfg.CmsConfig.UserId)
+	}
+
+	// the last known routing is kept if the file can not be read
+	writeTestFile(t, filename, `{broken`)
+	client.config = &unwindiaConfig.Config{CmsConfig: unwindiaConfig.CmsConfig{UserId: 5}}
+	cfg = c.GetConfig()
+	if got, ok := cfg.ForumRouting.ForumID("csgo", ""); got != 11 || !ok || cfg.CmsConfig.UserId != 5 {
+		t.Errorf("GetConfig() with broken file = %v, %v, userId %v, want 11, true, userId 5", got, ok, cfg.CmsConfig.UserId)
+	}
+}
+
+func TestNewConfigFile_error(t *testing.T) {
+	dir := t.TempDir()
+	client := &testUnwindiaConfigClient{config: &unwindiaConfig.Config{}}
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "err-missing"},
+		{name: "err-broken", content: `{broken`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := path.Join(dir, tt.name+".json")
+			if tt.content != "" {
+				writeTestFile(t, filename, tt.content)
+			}
+
+			if _, err := newConfigFile(client, filename); err == nil {
+				t.Errorf("newConfigFile() error = nil, want error")
+			}
+		})
+	}
+}
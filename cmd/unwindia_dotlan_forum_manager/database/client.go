@@ -3,17 +3,21 @@ package database
 import (
 	"context"
 	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/environment"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/metrics"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 	"time"
 )
 
 const (
-	CollectionName = "dotlan_forum_manager"
-	DatabaseName   = "unwindia"
-	DefaultTimeout = 10 * time.Second
+	CollectionName        = "dotlan_forum_manager"
+	ContestCollectionName = "dotlan_forum_manager_contests"
+	DatabaseName          = "unwindia"
+	DefaultTimeout        = 10 * time.Second
 )
 
 // DatabaseClient is the client-interface for the main mongodb database
@@ -24,6 +28,17 @@ type DatabaseClient interface {
 	Get(ctx context.Context, id string) (*DotlanForumStatus, error)
 	// List returns all existing DotlanForumStatus entries in a Result chan
 	List(ctx context.Context, filter interface{}, resultChan chan Result)
+	// ListContestHashes returns the hashes of the dotlan contests published by the last contest sync, keyed by tcid
+	ListContestHashes(ctx context.Context) (map[string]string, error)
+	// UpsertContestHash stores the hash of a published dotlan contest
+	UpsertContestHash(ctx context.Context, id, hash string) error
+	// Ping checks the connection to the mongodb server
+	Ping(ctx context.Context) error
+}
+
+// FilterWithError returns a List filter which matches all entries having a last error set
+func FilterWithError() interface{} {
+	return bson.D{{Key: "lastError", Value: bson.D{{Key: "$exists", Value: true}, {Key: "$ne", Value: ""}}}}
 }
 
 func NewClient(ctx context.Context, env *environment.Environment) (*DatabaseClientImpl, error) {
@@ -33,8 +48,9 @@ func NewClient(ctx context.Context, env *environment.Environment) (*DatabaseClie
 		return nil, err
 	}
 
-	ctx, _ = context.WithTimeout(ctx, 10*time.Second)
-	err = client.Connect(ctx)
+	connectCtx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+	err = client.Connect(connectCtx)
 	if err != nil {
 		log.Error().Err(err)
 		return nil, err
@@ -46,36 +62,45 @@ func NewClient(ctx context.Context, env *environment.Environment) (*DatabaseClie
 func NewClientWithDatabase(ctx context.Context, db *mongo.Database) (*DatabaseClientImpl, error) {
 
 	dbClient := DatabaseClientImpl{
-		ctx:        ctx,
-		collection: db.Collection(CollectionName),
+		ctx:               ctx,
+		collection:        db.Collection(CollectionName),
+		contestCollection: db.Collection(ContestCollectionName),
 	}
 
 	return &dbClient, nil
 }
 
 type DatabaseClientImpl struct {
-	ctx        context.Context
-	collection *mongo.Collection
+	ctx               context.Context
+	collection        *mongo.Collection
+	contestCollection *mongo.Collection
 }
 
 func (d DatabaseClientImpl) Upsert(ctx context.Context, entry *DotlanForumStatus) error {
+	defer prometheus.NewTimer(metrics.MongoDBQueryDuration.WithLabelValues("upsert")).ObserveDuration()
+
 	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
 	defer cancel()
 
-	filter := bson.D{{"_id", entry.ID}}
+	filter := bson.D{{Key: "_id", Value: entry.ID}}
 
 	updateResult, err := d.collection.ReplaceOne(ctx, filter, entry, options.Replace().SetUpsert(true))
+	if err != nil {
+		return err
+	}
 
 	log.Debug().Interface("updateResult", *updateResult).Msg("Update result")
 
-	return err
+	return nil
 }
 
 func (d DatabaseClientImpl) Get(ctx context.Context, id string) (*DotlanForumStatus, error) {
+	defer prometheus.NewTimer(metrics.MongoDBQueryDuration.WithLabelValues("get")).ObserveDuration()
+
 	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
 	defer cancel()
 
-	filter := bson.D{{"_id", id}}
+	filter := bson.D{{Key: "_id", Value: id}}
 	result := d.collection.FindOne(ctx, filter)
 	if result.Err() != nil {
 		return nil, result.Err()
@@ -91,6 +116,8 @@ func (d DatabaseClientImpl) Get(ctx context.Context, id string) (*DotlanForumSta
 }
 
 func (d DatabaseClientImpl) List(ctx context.Context, filter interface{}, resultChan chan Result) {
+	defer prometheus.NewTimer(metrics.MongoDBQueryDuration.WithLabelValues("list")).ObserveDuration()
+
 	var listResult []DotlanForumStatus
 
 	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
@@ -120,3 +147,45 @@ func (d DatabaseClientImpl) List(ctx context.Context, filter interface{}, result
 
 	resultChan <- Result{Result: listResult, Error: nil}
 }
+
+func (d DatabaseClientImpl) ListContestHashes(ctx context.Context) (map[string]string, error) {
+	defer prometheus.NewTimer(metrics.MongoDBQueryDuration.WithLabelValues("list_contest_hashes")).ObserveDuration()
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+
+	cur, err := d.contestCollection.Find(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	hashes := make(map[string]string)
+	for cur.Next(ctx) {
+		var entry ContestSyncState
+		if err := cur.Decode(&entry); err != nil {
+			log.Error().Err(err).Msg("Error decoding document")
+			continue
+		}
+		hashes[entry.ID] = entry.Hash
+	}
+
+	return hashes, cur.Err()
+}
+
+func (d DatabaseClientImpl) UpsertContestHash(ctx context.Context, id, hash string) error {
+	defer prometheus.NewTimer(metrics.MongoDBQueryDuration.WithLabelValues("upsert_contest_hash")).ObserveDuration()
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: id}}
+	entry := ContestSyncState{ID: id, Hash: hash, UpdatedAt: time.Now()}
+
+	_, err := d.contestCollection.ReplaceOne(ctx, filter, entry, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (d DatabaseClientImpl) Ping(ctx context.Context) error {
+	return d.collection.Database().Client().Ping(ctx, readpref.Primary())
+}
@@ -1,9 +1,19 @@
 package database
 
 import (
+	"errors"
+	"github.com/GSH-LAN/Unwindia_common/src/go/matchservice"
 	"time"
 )
 
+var (
+	// ErrNoMatchInfo is returned when an entry has no stored MatchInfo which could be rendered again
+	ErrNoMatchInfo = errors.New("no match info stored for entry")
+	// ErrUnknownLastEvent is returned when the stored last event of an entry is no known match event subtype, so the
+	// template to render it again can not be selected
+	ErrUnknownLastEvent = errors.New("unknown last event stored for entry")
+)
+
 type Result struct {
 	Result DotlanForumStatusList
 	Error  error
@@ -12,9 +22,36 @@ type Result struct {
 type DotlanForumStatusList []DotlanForumStatus
 
 type DotlanForumStatus struct {
-	ID                  string    `bson:"_id" json:"unwindiaMatchID"`
-	DotlanForumPostID   int       `bson:"dotlanForumPostID" json:"dotlanForumPostID"`
-	DotlanForumThreadID int       `bson:"dotlanForumThreadID" json:"dotlanForumThreadID"`
-	CreatedAt           time.Time `bson:"createdAt,omitempty"`
-	UpdatedAt           time.Time `bson:"updatedAt,omitempty"`
+	ID                  string                  `bson:"_id" json:"unwindiaMatchID"`
+	DotlanForumPostID   int                     `bson:"dotlanForumPostID" json:"dotlanForumPostID"`
+	DotlanForumThreadID int                     `bson:"dotlanForumThreadID" json:"dotlanForumThreadID"`
+	DotlanResultPostID  int                     `bson:"dotlanResultPostID,omitempty" json:"dotlanResultPostID,omitempty"`
+	DotlanForumID       int                     `bson:"dotlanForumID,omitempty" json:"dotlanForumID,omitempty"`
+	ThreadTitle         string                  `bson:"threadTitle,omitempty" json:"threadTitle,omitempty"`
+	ThreadClosed        bool                    `bson:"threadClosed,omitempty" json:"threadClosed"`
+	ThreadArchived      bool                    `bson:"threadArchived,omitempty" json:"threadArchived"`
+	LastSeenPostID      int                     `bson:"lastSeenPostID,omitempty" json:"lastSeenPostID,omitempty"`
+	CredentialsHash     string                  `bson:"credentialsHash,omitempty" json:"credentialsHash,omitempty"`
+	CredentialsSentAt   time.Time               `bson:"credentialsSentAt,omitempty" json:"credentialsSentAt,omitempty"`
+	MatchInfo           *matchservice.MatchInfo `bson:"matchInfo,omitempty" json:"matchInfo,omitempty"`
+	LastEvent           string                  `bson:"lastEvent,omitempty" json:"lastEvent,omitempty"`
+	ContentHash         string                  `bson:"contentHash,omitempty" json:"contentHash,omitempty"`
+	LastError           string                  `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	LastErrorAt         time.Time               `bson:"lastErrorAt,omitempty" json:"lastErrorAt,omitempty"`
+	LastDrift           string                  `bson:"lastDrift,omitempty" json:"lastDrift,omitempty"`
+	LastDriftAt         time.Time               `bson:"lastDriftAt,omitempty" json:"lastDriftAt,omitempty"`
+	CreatedAt           time.Time               `bson:"createdAt,omitempty" json:"createdAt"`
+	UpdatedAt           time.Time               `bson:"updatedAt,omitempty" json:"updatedAt"`
+}
+
+// HasForumPost returns true if the entry points to an existing thread and post within dotlan
+func (d *DotlanForumStatus) HasForumPost() bool {
+	return d.DotlanForumThreadID > 0 && d.DotlanForumPostID > 0
+}
+
+// ContestSyncState is the hash of a dotlan contest as it was last published by the contest sync
+type ContestSyncState struct {
+	ID        string    `bson:"_id" json:"tcid"`
+	Hash      string    `bson:"hash" json:"hash"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
 }
@@ -3,14 +3,17 @@ package dotlan
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"github.com/GSH-LAN/Unwindia_common/src/go/config"
 	"github.com/GSH-LAN/Unwindia_common/src/go/matchservice"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/config"
 	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/environment"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/metrics"
 	sq "github.com/Masterminds/squirrel"
 	"github.com/gammazero/workerpool"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog/log"
 	"sync"
 	"time"
@@ -20,9 +23,46 @@ const (
 	dotlanForumExt = "turnier"
 )
 
+var (
+	// ErrForumPostNotFound is returned if a forum post which should be updated does not exist within dotlan
+	ErrForumPostNotFound = errors.New("forum post not found")
+	// ErrForumThreadNotFound is returned if a forum thread which should be closed does not exist within dotlan
+	ErrForumThreadNotFound = errors.New("forum thread not found")
+	// ErrNoArchiveForum is returned if threads should be archived but no archive forum is configured
+	ErrNoArchiveForum = errors.New("no archive forum configured")
+	// ErrUserNotFound is returned if the configured cms user does not exist within dotlan
+	ErrUserNotFound = errors.New("dotlan user not found")
+)
+
 type DotlanDbClient interface {
-	UpsertForumPostForMatch(ctx context.Context, matchInfo *matchservice.MatchInfo, text string) (int, int, error)
-	UpdateForumPostForMatch(ctx context.Context, postId int, text string) error
+	// CreateForumPostForMatch creates the post of the bot user within the thread of the match and creates the thread
+	// if none exists yet. If the bot user already has a post in the thread apart from the result post, that post is
+	// updated instead, so a redelivered creation never duplicates the post.
+	CreateForumPostForMatch(ctx context.Context, matchInfo *matchservice.MatchInfo, title string, text PostText, resultPostId int) (int, int, error)
+	UpdateForumPostForMatch(ctx context.Context, postId int, text PostText) error
+	// UpdateForumThreadTitle sets the title of the forum thread
+	UpdateForumThreadTitle(ctx context.Context, threadId int, title string) error
+	// CloseForumThreadForMatch posts the result text as reply of the bot user and closes the thread afterwards. No
+	// result is posted if the text is empty. If lockPost is set, the forum post with postId is locked as well.
+	CloseForumThreadForMatch(ctx context.Context, matchInfo *matchservice.MatchInfo, threadId, postId int, resultText PostText, lockPost bool) (int, error)
+	// ArchiveForumThread moves the forum thread to the given forum and hides it from the latest threads
+	ArchiveForumThread(ctx context.Context, threadId, forumId int) error
+	// SendPrivateMessages sends a private message of the bot user to every given user
+	SendPrivateMessages(ctx context.Context, userIds []uint, subject, text string) error
+	// ListForumReplies returns the posts of the thread after the given post id, which were not written by the bot user
+	ListForumReplies(ctx context.Context, threadId, afterPostId int) ([]ForumReply, error)
+	// ListContests reads all contests of the tournaments matching the tournament filter of the config from dotlan
+	ListContests(ctx context.Context) ([]Contest, error)
+	// ForumIdForMatch returns the forum in which the thread of the match is created
+	ForumIdForMatch(matchInfo *matchservice.MatchInfo) int
+	// GetMatchData reads the contest of the match with its teams and their members
+	GetMatchData(ctx context.Context, tcid string) (*MatchData, error)
+	// GetThreadForumId returns the forum the thread of the match is located in within dotlan
+	GetThreadForumId(ctx context.Context, matchId string) (int, error)
+	// CheckForumPost checks if the forum thread and the forum post with the given ids still exist
+	CheckForumPost(ctx context.Context, threadId, postId int) (threadExists bool, postExists bool, err error)
+	// Ping checks the connection to the dotlan database
+	Ping(ctx context.Context) error
 }
 
 type DotlanDbClientImpl struct {
@@ -32,156 +72,500 @@ type DotlanDbClientImpl struct {
 	lock            sync.Mutex
 	workerpool      *workerpool.WorkerPool
 	modelFieldCache map[string]string
+	nickCache       map[uint]string
 	config          config.ConfigClient
 }
 
-func (d *DotlanDbClientImpl) UpsertForumPostForMatch(ctx context.Context, matchInfo *matchservice.MatchInfo, text string) (threadId int, postId int, err error) {
-	// begin transaction
-	tx, err := d.db.Beginx()
-	if err != nil {
-		return
-	}
+func (d *DotlanDbClientImpl) CreateForumPostForMatch(ctx context.Context, matchInfo *matchservice.MatchInfo, title string, text PostText, resultPostId int) (threadId int, postId int, err error) {
+	defer prometheus.NewTimer(metrics.MySQLQueryDuration.WithLabelValues("create_forum_post")).ObserveDuration()
+
+	var operations []string
 
-	commit := false
+	err = d.withTx(ctx, func(tx *sqlx.Tx) error {
+		operations = nil
 
-	defer func() {
-		if commit {
-			err = tx.Commit()
-		} else {
-			err = tx.Rollback()
+		id, created, err := d.getOrCreateThread(ctx, tx, matchInfo, title)
+		if err != nil {
+			return err
+		}
+		threadId = id
+		if created {
+			operations = append(operations, metrics.OperationThreadCreated)
 		}
-	}()
 
-	// check if we have an existing thread
-	qry := "select threadid from forum_thread where ext_id = ? order by threadid LIMIT 1"
-	log.Debug().Str("query", qry).Str("ext_id", matchInfo.MsID).Msg("prepared query for getting thread")
+		existingPostId, err := d.getBotPost(ctx, tx, threadId, resultPostId)
+		if err != nil {
+			return err
+		}
+		if existingPostId > 0 {
+			// the post was created before, e.g. by a delivery whose state could not be stored
+			log.Info().Int("threadId", threadId).Int("postId", existingPostId).Str("contestId", matchInfo.MsID).Msg("Updating existing post of match thread")
 
-	stmt, err := tx.PreparexContext(ctx, qry)
-	if err != nil {
-		return 0, 0, err
-	}
+			if _, err = d.updatePostText(ctx, tx, existingPostId, text); err != nil {
+				return err
+			}
+			postId = existingPostId
+			operations = append(operations, metrics.OperationPostUpdated)
 
-	row := stmt.QueryRowxContext(ctx, matchInfo.MsID)
-	err = row.Scan(&threadId)
-	if err != nil {
-		if err != sql.ErrNoRows {
-			log.Error().Err(err).Msg("error scanning thread")
-			return 0, 0, err
+			return nil
 		}
-		log.Debug().Err(err).Msg("we have no thread")
 
-		// we have no thread, so we need to create one
-		qry, args, err := sq.Insert(ForumThread{}.TableName()).
-			Columns("title", "forumid", "lastposttime", "replies", "hits", "ext", "ext_id").
-			Values(matchInfo.MatchTitle, d.env.DotlanContestForumThreadId, time.Now(), 1, 1, dotlanForumExt, matchInfo.MsID).
-			ToSql()
+		log.Info().Int("threadId", threadId).Str("contestId", matchInfo.MsID).Msgf("Creating post for match thread")
+
+		postId, err = d.insertPost(ctx, tx, threadId, text)
 		if err != nil {
-			log.Error().Err(err).Msg("error creating new thread sql")
-			return 0, 0, err
+			return err
 		}
-		log.Debug().Str("query", qry).Interface("args", args).Msg("create new thread query")
+		operations = append(operations, metrics.OperationPostCreated)
 
-		insertResult, err := tx.ExecContext(ctx, qry, args...)
-		if err != nil {
-			log.Error().Err(err).Msg("error creating new thread")
-			return 0, 0, err
+		if err = d.touchThread(ctx, tx, threadId); err != nil {
+			return err
 		}
 
-		id, err := insertResult.LastInsertId()
+		qry := "update t_contest set comments = comments+1 where tcid = ?"
+		_, err = tx.ExecContext(ctx, qry, matchInfo.MsID)
 		if err != nil {
-			log.Error().Err(err).Msg("error getting last inserted threadid")
-			return 0, 0, err
+			log.Error().Err(err).Msg("error updating t_contest")
+			return err
 		}
 
-		threadId = int(id)
-		log.Info().Int("threadId", threadId).Msg("created new thread")
-	} else {
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, operation := range operations {
+		metrics.ForumWrites.WithLabelValues(operation).Inc()
+	}
+
+	return threadId, postId, nil
+}
+
+// getOrCreateThread returns the id of the thread of the match and creates a new thread if none exists yet. The title of
+// an existing thread is updated if it differs.
+func (d *DotlanDbClientImpl) getOrCreateThread(ctx context.Context, tx *sqlx.Tx, matchInfo *matchservice.MatchInfo, title string) (threadId int, created bool, err error) {
+	// check if we have an existing thread
+	qry := "select threadid, title from forum_thread where ext_id = ? order by threadid LIMIT 1"
+	log.Debug().Str("query", qry).Str("ext_id", matchInfo.MsID).Msg("prepared query for getting thread")
+
+	var thread ForumThread
+	err = tx.GetContext(ctx, &thread, qry, matchInfo.MsID)
+	if err == nil {
+		threadId = int(thread.Threadid)
 		log.Info().Int("threadId", threadId).Msg("found existing thread")
+
+		if thread.Title != title {
+			qry = "update forum_thread set title = ? where threadid = ?"
+			if _, err = tx.ExecContext(ctx, qry, title, threadId); err != nil {
+				log.Error().Err(err).Msg("error updating thread title")
+				return 0, false, err
+			}
+			log.Info().Int("threadId", threadId).Str("title", title).Msg("updated thread title")
+		}
+
+		return threadId, false, nil
 	}
+	if err != sql.ErrNoRows {
+		log.Error().Err(err).Msg("error scanning thread")
+		return 0, false, err
+	}
+	log.Debug().Err(err).Msg("we have no thread")
 
-	log.Info().Int("threadId", threadId).Str("contestId", matchInfo.MsID).Msgf("Upserting post for match thread")
+	// we have no thread, so we need to create one
+	userId := d.config.GetConfig().CmsConfig.UserId
+	nick, err := d.getUserNick(ctx, tx, userId)
+	if err != nil {
+		return 0, false, err
+	}
 
-	qry = "select postid from forum_post where threadid = ? and userid = ? order by postid LIMIT 1"
-	log.Debug().Str("query", qry).Int("threadid", threadId).Uint("userid", d.config.GetConfig().CmsConfig.UserId).Msg("prepared query for getting post")
+	qry, args, err := sq.Insert(ForumThread{}.TableName()).
+		Columns("title", "forumid", "user_id", "firstposter", "lastposter", "lastposttime", "replies", "hits", "ext", "ext_id").
+		Values(title, d.ForumIdForMatch(matchInfo), userId, nick, nick, time.Now(), 1, 1, dotlanForumExt, matchInfo.MsID).
+		ToSql()
+	if err != nil {
+		log.Error().Err(err).Msg("error creating new thread sql")
+		return 0, false, err
+	}
+	log.Debug().Str("query", qry).Interface("args", args).Msg("create new thread query")
 
-	stmt, err = tx.PreparexContext(ctx, qry)
+	insertResult, err := tx.ExecContext(ctx, qry, args...)
 	if err != nil {
-		return 0, 0, err
+		log.Error().Err(err).Msg("error creating new thread")
+		return 0, false, err
 	}
 
-	row = stmt.QueryRowxContext(ctx, threadId, d.config.GetConfig().CmsConfig.UserId)
-	err = row.Scan(&postId)
+	id, err := insertResult.LastInsertId()
 	if err != nil {
-		if err != sql.ErrNoRows {
-			log.Error().Err(err).Msg("error scanning post")
-			return 0, 0, err
-		}
-		log.Debug().Err(err).Msg("we have no post")
+		log.Error().Err(err).Msg("error getting last inserted threadid")
+		return 0, false, err
+	}
+
+	threadId = int(id)
+	log.Info().Int("threadId", threadId).Msg("created new thread")
+
+	return threadId, true, nil
+}
 
-		qry, args, err := sq.Insert(ForumPost{}.TableName()).
-			Columns("threadid", "userid", "dateline", "htmltext").
-			Values(threadId, d.config.GetConfig().CmsConfig.UserId, time.Now(), text).
-			ToSql()
+// ForumIdForMatch returns the forum in which the thread of the match is created. It falls back to the forum of
+// DOTLAN_CONTEST_FORUM_THREAD_ID if no route of the routing table matches.
+func (d *DotlanDbClientImpl) ForumIdForMatch(matchInfo *matchservice.MatchInfo) int {
+	game := matchInfo.Game
+	if game == "" {
+		game = d.config.GetConfig().CmsConfig.DefaultGame
+	}
+
+	if forumId, ok := d.config.GetConfig().ForumRouting.ForumID(game, matchInfo.TournamentName); ok {
+		log.Debug().Str("game", game).Str("tournament", matchInfo.TournamentName).Int("forumId", forumId).Msg("routed thread to forum")
+		return forumId
+	}
+
+	return d.env.DotlanContestForumThreadId
+}
+
+// getBotPost returns the first post of the bot user within the thread, ignoring the result post, or 0 if the bot user
+// has no post in the thread
+func (d *DotlanDbClientImpl) getBotPost(ctx context.Context, tx *sqlx.Tx, threadId, resultPostId int) (int, error) {
+	userId := d.config.GetConfig().CmsConfig.UserId
+
+	qry := "select postid from forum_post where threadid = ? and userid = ? and postid <> ? order by postid LIMIT 1"
+	log.Debug().Str("query", qry).Int("threadid", threadId).Uint("userid", userId).Msg("prepared query for getting post")
+
+	var postId int
+	err := tx.GetContext(ctx, &postId, qry, threadId, userId, resultPostId)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("error scanning post")
+		return 0, err
+	}
+
+	return postId, nil
+}
+
+// insertPost creates a new post of the bot user within the thread. Besides the html the BBCode source of the text is
+// stored, which is loaded when the post is edited within dotlan.
+func (d *DotlanDbClientImpl) insertPost(ctx context.Context, tx *sqlx.Tx, threadId int, text PostText) (int, error) {
+	qry, args, err := sq.Insert(ForumPost{}.TableName()).
+		Columns("threadid", "userid", "dateline", "pagetext", "htmltext").
+		Values(threadId, d.config.GetConfig().CmsConfig.UserId, time.Now(), text.Pagetext, text.Htmltext).
+		ToSql()
+	if err != nil {
+		log.Error().Err(err).Msg("error creating new post sql")
+		return 0, err
+	}
+	// the args hold the post text, which may contain sensitive values of private forums
+	log.Debug().Str("query", qry).Int("threadId", threadId).Msg("create new post query")
+
+	insertResult, err := tx.ExecContext(ctx, qry, args...)
+	if err != nil {
+		log.Error().Err(err).Msg("error creating new post")
+		return 0, err
+	}
+
+	id, err := insertResult.LastInsertId()
+	if err != nil {
+		log.Error().Err(err).Msg("error getting last inserted postid")
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// updatePostText sets the html and BBCode source text of the post and appends the edit of the bot user to the history of the post. It returns
+// the id of the thread of the post.
+func (d *DotlanDbClientImpl) updatePostText(ctx context.Context, tx *sqlx.Tx, postId int, text PostText) (int, error) {
+	var post struct {
+		Threadid int            `db:"threadid"`
+		History  sql.NullString `db:"history"`
+	}
+
+	qry := "select threadid, history from forum_post where postid = ? for update"
+	err := tx.GetContext(ctx, &post, qry, postId)
+	if err == sql.ErrNoRows {
+		return 0, ErrForumPostNotFound
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("error getting post for update")
+		return 0, err
+	}
+
+	userId := d.config.GetConfig().CmsConfig.UserId
+	nick, err := d.getUserNick(ctx, tx, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	history, err := appendHistory(post.History.String, HistoryEntry{UserId: userId, Nick: nick, Dateline: time.Now()})
+	if err != nil {
+		// never drop edits written by dotlan itself, the post is updated without history entry instead
+		log.Warn().Err(err).Int("postId", postId).Msg("could not append to post history, keeping history untouched")
+		history = post.History.String
+	}
+
+	qry = "update forum_post set pagetext = ?, htmltext = ?, history = ? where postid = ?"
+	if _, err = tx.ExecContext(ctx, qry, text.Pagetext, text.Htmltext, history, postId); err != nil {
+		log.Error().Err(err).Msg("error updating post")
+		return 0, err
+	}
+
+	return post.Threadid, nil
+}
+
+// touchThread updates the last post metadata of the thread from its newest post, like the dotlan forum does on every
+// new post: the author of the newest post becomes the last poster, its dateline the last post time and the replies are
+// recounted. Edits of an existing post therefore never bump the thread.
+func (d *DotlanDbClientImpl) touchThread(ctx context.Context, tx *sqlx.Tx, threadId int) error {
+	var replies int
+	qry := "select count(*) from forum_post where threadid = ?"
+	if err := tx.GetContext(ctx, &replies, qry, threadId); err != nil {
+		log.Error().Err(err).Msg("error counting posts of thread")
+		return err
+	}
+
+	var lastPost struct {
+		Nick     string    `db:"nick"`
+		Dateline time.Time `db:"dateline"`
+	}
+	qry = fmt.Sprintf("select coalesce(u.nick, '') as nick, p.dateline from %s p left join `%s` u on u.id = p.userid "+
+		"where p.threadid = ? order by p.postid desc LIMIT 1", ForumPost{}.TableName(), User{}.TableName())
+	if err := tx.GetContext(ctx, &lastPost, qry, threadId); err != nil {
+		log.Error().Err(err).Msg("error getting newest post of thread")
+		return err
+	}
+
+	qry, args, err := sq.Update(ForumThread{}.TableName()).
+		Set("lastposter", lastPost.Nick).
+		Set("lastposttime", lastPost.Dateline).
+		Set("replies", replies).
+		Where(sq.Eq{"threadid": threadId}).
+		ToSql()
+	if err != nil {
+		log.Error().Err(err).Msg("error creating thread update sql")
+		return err
+	}
+	log.Debug().Str("query", qry).Interface("args", args).Msg("update thread query")
+
+	if _, err = tx.ExecContext(ctx, qry, args...); err != nil {
+		log.Error().Err(err).Msg("error updating thread")
+		return err
+	}
+
+	return nil
+}
+
+// getUserNick returns the nickname of the dotlan user with the given id. The nicknames are cached for the lifetime of
+// the client, as only the nickname of the bot user is read.
+func (d *DotlanDbClientImpl) getUserNick(ctx context.Context, tx *sqlx.Tx, userId uint) (string, error) {
+	d.lock.Lock()
+	nick, ok := d.nickCache[userId]
+	d.lock.Unlock()
+	if ok {
+		return nick, nil
+	}
+
+	qry := fmt.Sprintf("select nick from `%s` where id = ?", User{}.TableName())
+	err := tx.GetContext(ctx, &nick, qry, userId)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("%w: %d", ErrUserNotFound, userId)
+	}
+	if err != nil {
+		log.Error().Err(err).Uint("userId", userId).Msg("error getting user nick")
+		return "", err
+	}
+
+	d.lock.Lock()
+	d.nickCache[userId] = nick
+	d.lock.Unlock()
+
+	return nick, nil
+}
+
+func (d *DotlanDbClientImpl) UpdateForumPostForMatch(ctx context.Context, postId int, text PostText) error {
+	defer prometheus.NewTimer(metrics.MySQLQueryDuration.WithLabelValues("update_forum_post")).ObserveDuration()
+
+	err := d.withTx(ctx, func(tx *sqlx.Tx) error {
+		threadId, err := d.updatePostText(ctx, tx, postId, text)
 		if err != nil {
-			log.Error().Err(err).Msg("error creating new post sql")
-			return 0, 0, err
+			return err
 		}
-		log.Debug().Str("query", qry).Interface("args", args).Msg("create new post query")
 
-		_, err = tx.ExecContext(ctx, qry, args...)
-		if err != nil {
-			log.Error().Err(err).Msg("error creating new post")
-			return 0, 0, err
+		return d.touchThread(ctx, tx, threadId)
+	})
+	if err != nil {
+		return err
+	}
+
+	metrics.ForumWrites.WithLabelValues(metrics.OperationPostUpdated).Inc()
+	return nil
+}
+
+func (d *DotlanDbClientImpl) UpdateForumThreadTitle(ctx context.Context, threadId int, title string) error {
+	defer prometheus.NewTimer(metrics.MySQLQueryDuration.WithLabelValues("update_forum_thread_title")).ObserveDuration()
+
+	qry := "update forum_thread set title = ? where threadid = ?"
+	result, err := d.db.ExecContext(ctx, qry, title, threadId)
+	if err != nil {
+		return err
+	}
+
+	// rows affected reports matched rows since the connection uses clientFoundRows
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrForumThreadNotFound
+	}
+
+	metrics.ForumWrites.WithLabelValues(metrics.OperationTitleUpdated).Inc()
+	return nil
+}
+
+func (d *DotlanDbClientImpl) CloseForumThreadForMatch(ctx context.Context, matchInfo *matchservice.MatchInfo, threadId, postId int, resultText PostText, lockPost bool) (resultPostId int, err error) {
+	defer prometheus.NewTimer(metrics.MySQLQueryDuration.WithLabelValues("close_forum_thread")).ObserveDuration()
+
+	err = d.withTx(ctx, func(tx *sqlx.Tx) error {
+		resultPostId = 0
+
+		if !resultText.IsEmpty() {
+			id, err := d.insertPost(ctx, tx, threadId, resultText)
+			if err != nil {
+				return err
+			}
+			resultPostId = id
+
+			if err = d.touchThread(ctx, tx, threadId); err != nil {
+				return err
+			}
+
+			qry := "update t_contest set comments = comments+1 where tcid = ?"
+			if _, err = tx.ExecContext(ctx, qry, matchInfo.MsID); err != nil {
+				log.Error().Err(err).Msg("error updating t_contest")
+				return err
+			}
 		}
 
-		qry = "update t_contest set comments = comments+1 where tcid = ?"
-		_, err = tx.ExecContext(ctx, qry, matchInfo.MsID)
+		qry := "update forum_thread set closed = 1 where threadid = ?"
+		result, err := tx.ExecContext(ctx, qry, threadId)
 		if err != nil {
-			log.Error().Err(err).Msg("error updating t_contest")
-			return 0, 0, err
+			log.Error().Err(err).Msg("error closing thread")
+			return err
 		}
-	} else {
-		//we have an existing post
-		log.Debug().Int("postid", postId).Msg("found existing post which will be updated")
 
-		qry = "update forum_post set htmltext = ? where postid = ?"
-		_, err = tx.ExecContext(ctx, qry, text, postId)
+		affected, err := result.RowsAffected()
 		if err != nil {
-			log.Error().Err(err).Msg("error updating t_contest")
-			return 0, 0, err
+			return err
+		}
+		if affected == 0 {
+			return ErrForumThreadNotFound
 		}
+
+		if lockPost {
+			qry = "update forum_post set locked = 1 where postid = ?"
+			result, err = tx.ExecContext(ctx, qry, postId)
+			if err != nil {
+				log.Error().Err(err).Msg("error locking post")
+				return err
+			}
+
+			affected, err = result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if affected == 0 {
+				return ErrForumPostNotFound
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
 	}
 
-	return
+	if resultPostId > 0 {
+		metrics.ForumWrites.WithLabelValues(metrics.OperationPostCreated).Inc()
+	}
+	metrics.ForumWrites.WithLabelValues(metrics.OperationThreadClosed).Inc()
+	if lockPost {
+		metrics.ForumWrites.WithLabelValues(metrics.OperationPostLocked).Inc()
+	}
+
+	log.Info().Int("threadId", threadId).Int("resultPostId", resultPostId).Bool("postLocked", lockPost).Msg("closed thread")
+
+	return resultPostId, nil
 }
 
-func (d *DotlanDbClientImpl) UpdateForumPostForMatch(ctx context.Context, postId int, text string) error {
-	// begin transaction
-	tx, err := d.db.Beginx()
+func (d *DotlanDbClientImpl) ArchiveForumThread(ctx context.Context, threadId, forumId int) error {
+	defer prometheus.NewTimer(metrics.MySQLQueryDuration.WithLabelValues("archive_forum_thread")).ObserveDuration()
+
+	qry := "update forum_thread set forumid = ?, show_latest = 0 where threadid = ?"
+	result, err := d.db.ExecContext(ctx, qry, forumId, threadId)
 	if err != nil {
 		return err
 	}
 
-	commit := false
-
-	defer func() {
-		if commit {
-			err = tx.Commit()
-		} else {
-			err = tx.Rollback()
-		}
-	}()
-
-	qry := "update forum_post set htmltext = ? where postid = ?"
-	_, err = tx.ExecContext(ctx, qry, text, postId)
+	// rows affected reports matched rows since the connection uses clientFoundRows
+	affected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
+	if affected == 0 {
+		return ErrForumThreadNotFound
+	}
+
+	metrics.ForumWrites.WithLabelValues(metrics.OperationThreadArchived).Inc()
+	log.Info().Int("threadId", threadId).Int("forumId", forumId).Msg("archived thread")
+
 	return nil
 }
 
+func (d *DotlanDbClientImpl) CheckForumPost(ctx context.Context, threadId, postId int) (threadExists bool, postExists bool, err error) {
+	defer prometheus.NewTimer(metrics.MySQLQueryDuration.WithLabelValues("check_forum_post")).ObserveDuration()
+
+	var count int
+	qry := "select count(*) from forum_thread where threadid = ?"
+	if err = d.db.GetContext(ctx, &count, qry, threadId); err != nil {
+		return false, false, err
+	}
+	threadExists = count > 0
+
+	qry = "select count(*) from forum_post where postid = ? and threadid = ?"
+	if err = d.db.GetContext(ctx, &count, qry, postId, threadId); err != nil {
+		return false, false, err
+	}
+	postExists = count > 0
+
+	return threadExists, postExists, nil
+}
+
+// GetThreadForumId returns the forum the thread of the match is located in within dotlan. It returns
+// ErrForumThreadNotFound if the match has no thread yet.
+func (d *DotlanDbClientImpl) GetThreadForumId(ctx context.Context, matchId string) (int, error) {
+	defer prometheus.NewTimer(metrics.MySQLQueryDuration.WithLabelValues("get_thread_forum_id")).ObserveDuration()
+
+	var forumId int
+	qry := "select forumid from forum_thread where ext_id = ? order by threadid LIMIT 1"
+	err := d.db.GetContext(ctx, &forumId, qry, matchId)
+	if err == sql.ErrNoRows {
+		return 0, ErrForumThreadNotFound
+	}
+	if err != nil {
+		log.Error().Err(err).Str("matchId", matchId).Msg("error getting forum of thread")
+		return 0, err
+	}
+
+	return forumId, nil
+}
+
+func (d *DotlanDbClientImpl) Ping(ctx context.Context) error {
+	return d.db.PingContext(ctx)
+}
+
 func NewClient(ctx context.Context, env *environment.Environment, wp *workerpool.WorkerPool, config config.ConfigClient) (DotlanDbClient, error) {
-	sqlxDsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true",
+	sqlxDsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&clientFoundRows=true",
 		env.DotlanMySQLUser,
 		env.DotlanMySQLPassword,
 		env.DotlanMySQLHost,
@@ -201,5 +585,6 @@ func NewClient(ctx context.Context, env *environment.Environment, wp *workerpool
 		workerpool:      wp,
 		config:          config,
 		modelFieldCache: make(map[string]string),
+		nickCache:       make(map[uint]string),
 	}, nil
 }
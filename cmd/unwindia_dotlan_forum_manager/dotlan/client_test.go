@@ -0,0 +1,1063 @@
+package dotlan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	unwindiaConfig "github.com/GSH-LAN/Unwindia_common/src/go/config"
+	"github.com/GSH-LAN/Unwindia_common/src/go/matchservice"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/config"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/environment"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/routing"
+	sqle "github.com/dolthub/go-mysql-server"
+	"github.com/dolthub/go-mysql-server/memory"
+	"github.com/dolthub/go-mysql-server/server"
+	"github.com/dolthub/go-mysql-server/sql/information_schema"
+	"github.com/jmoiron/sqlx"
+	"log"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	testDatabaseName = "dotlan"
+	testUserId       = 3
+	testUserNick     = "unwindia"
+	testForumId      = 9
+)
+
+// testSchema contains the parts of the dotlan schema which are used by the client
+var testSchema = []string{
+	`CREATE TABLE forum_thread (
+		threadid int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		title varchar(255) NOT NULL DEFAULT '',
+		forumid int NOT NULL DEFAULT 0,
+		closed tinyint NOT NULL DEFAULT 0,
+		invisible tinyint NOT NULL DEFAULT 0,
+		show_latest tinyint NOT NULL DEFAULT 1,
+		sticky tinyint NOT NULL DEFAULT 0,
+		user_id int NOT NULL DEFAULT 0,
+		firstposter varchar(255) NOT NULL DEFAULT '',
+		lastposter varchar(255) NOT NULL DEFAULT '',
+		lastposttime datetime,
+		replies int NOT NULL DEFAULT 0,
+		hits int NOT NULL DEFAULT 0,
+		fv_id int NOT NULL DEFAULT 0,
+		ext varchar(64) NOT NULL DEFAULT '',
+		ext_id varchar(64) NOT NULL DEFAULT ''
+	)`,
+	`CREATE TABLE forum_post (
+		postid int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		threadid int NOT NULL DEFAULT 0,
+		userid int NOT NULL DEFAULT 0,
+		dateline datetime,
+		pagetext text,
+		htmltext text,
+		remoteip varchar(64) NOT NULL DEFAULT '',
+		dnsname varchar(255) NOT NULL DEFAULT '',
+		locked tinyint NOT NULL DEFAULT 0,
+		invisible tinyint NOT NULL DEFAULT 0,
+		history text,
+		attachment_i int NOT NULL DEFAULT 0,
+		notify int NOT NULL DEFAULT 0,
+		quoteid int NOT NULL DEFAULT 0
+	)`,
+	`CREATE TABLE user (
+		id int NOT NULL PRIMARY KEY,
+		nick varchar(255) NOT NULL DEFAULT ''
+	)`,
+	fmt.Sprintf("INSERT INTO user (id, nick) VALUES (%d, '%s')", testUserId, testUserNick),
+	`CREATE TABLE t_contest (
+		tcid int NOT NULL PRIMARY KEY,
+		tid int NOT NULL DEFAULT 0,
+		tcrunde int NOT NULL DEFAULT 0,
+		tcrow int NOT NULL DEFAULT 0,
+		team_a int NOT NULL DEFAULT 0,
+		team_b int NOT NULL DEFAULT 0,
+		won int NOT NULL DEFAULT 0,
+		starttime datetime,
+		comments int NOT NULL DEFAULT 0
+	)`,
+	`CREATE TABLE t_turnier (
+		tid int NOT NULL PRIMARY KEY,
+		teventid int NOT NULL DEFAULT 0,
+		tgameserver tinyint NOT NULL DEFAULT 0
+	)`,
+	`CREATE TABLE t_teilnehmer (
+		tnid int NOT NULL PRIMARY KEY,
+		tid int NOT NULL DEFAULT 0,
+		name varchar(255) NOT NULL DEFAULT ''
+	)`,
+	`CREATE TABLE t_teilnehmer_part (
+		tnid int NOT NULL,
+		user_id int NOT NULL
+	)`,
+	`CREATE TABLE user_pm (
+		id int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		from_id int NOT NULL DEFAULT 0,
+		to_id int NOT NULL DEFAULT 0,
+		subject varchar(255) NOT NULL DEFAULT '',
+		text text,
+		dateline datetime,
+		unread tinyint NOT NULL DEFAULT 1
+	)`,
+}
+
+// testDb is a connection to an in-memory MySQL compatible server, which is started once for all tests
+var testDb *sqlx.DB
+
+type testConfigClient struct {
+	config *config.Config
+}
+
+func (t testConfigClient) GetConfig() *config.Config {
+	return t.config
+}
+
+func TestMain(m *testing.M) {
+	engine := sqle.NewDefault(memory.NewMemoryDBProvider(
+		memory.NewDatabase(testDatabaseName),
+		information_schema.NewInformationSchemaDatabase(),
+	))
+
+	srv, err := server.NewDefaultServer(server.Config{Protocol: "tcp", Address: "127.0.0.1:0"}, engine)
+	if err != nil {
+		log.Fatal(err)
+	}
+	go func() {
+		if err := srv.Start(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	testDb, err = sqlx.Connect("mysql", fmt.Sprintf("root@tcp(%s)/%s?parseTime=true&clientFoundRows=true", srv.Listener.Addr().String(), testDatabaseName))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, stmt := range testSchema {
+		if _, err = testDb.Exec(stmt); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	code := m.Run()
+
+	_ = testDb.Close()
+	_ = srv.Close()
+	os.Exit(code)
+}
+
+func newTestClient() *DotlanDbClientImpl {
+	env := &environment.Environment{}
+	env.DotlanContestForumThreadId = testForumId
+
+	return &DotlanDbClientImpl{
+		ctx: context.Background(),
+		db:  testDb,
+		env: env,
+		config: testConfigClient{config: &config.Config{
+			Config: unwindiaConfig.Config{
+				CmsConfig: unwindiaConfig.CmsConfig{UserId: testUserId},
+			},
+		}},
+		modelFieldCache: make(map[string]string),
+		nickCache:       make(map[uint]string),
+	}
+}
+
+func getTestThread(t *testing.T, threadId int) ForumThread {
+	var thread ForumThread
+	if err := testDb.Get(&thread, "select threadid, title, forumid, closed, show_latest, user_id, firstposter, lastposter, lastposttime, replies, ext, ext_id from forum_thread where threadid = ?", threadId); err != nil {
+		t.Fatalf("error getting thread %d: %v", threadId, err)
+	}
+	return thread
+}
+
+// testPostText returns a post text with the same html and BBCode source
+func testPostText(text string) PostText {
+	return PostText{Pagetext: text, Htmltext: text}
+}
+
+func getTestPost(t *testing.T, postId int) ForumPost {
+	var post ForumPost
+	if err := testDb.Get(&post, "select postid, threadid, userid, pagetext, htmltext, locked from forum_post where postid = ?", postId); err != nil {
+		t.Fatalf("error getting post %d: %v", postId, err)
+	}
+	return post
+}
+
+func TestDotlanDbClientImpl_CreateForumPostForMatch(t *testing.T) {
+	d := newTestClient()
+	ctx := context.Background()
+
+	matchInfo := &matchservice.MatchInfo{MsID: "1001", MatchTitle: "cool-team vs nice-team"}
+	if _, err := testDb.Exec("insert into t_contest (tcid, comments) values (1001, 0)"); err != nil {
+		t.Fatal(err)
+	}
+
+	threadId, postId, err := d.CreateForumPostForMatch(ctx, matchInfo, matchInfo.MatchTitle, PostText{Pagetext: "[b]first[/b] text", Htmltext: "<b>first</b> text"}, 0)
+	if err != nil {
+		t.Fatalf("CreateForumPostForMatch() error = %v", err)
+	}
+	if threadId == 0 || postId == 0 {
+		t.Fatalf("CreateForumPostForMatch() threadId = %v, postId = %v, want ids", threadId, postId)
+	}
+
+	thread := getTestThread(t, threadId)
+	if thread.Title != matchInfo.MatchTitle || thread.Forumid != testForumId || thread.Ext != dotlanForumExt {
+		t.Errorf("CreateForumPostForMatch() persisted thread = %+v", thread)
+	}
+	if thread.User_id != testUserId || thread.Firstposter != testUserNick || thread.Lastposter != testUserNick || thread.Replies != 1 {
+		t.Errorf("CreateForumPostForMatch() persisted thread metadata = %+v", thread)
+	}
+
+	post := getTestPost(t, postId)
+	if int(post.Threadid) != threadId || post.Userid != testUserId || post.Htmltext != "<b>first</b> text" || post.Pagetext != "[b]first[/b] text" {
+		t.Errorf("CreateForumPostForMatch() persisted post = %+v", post)
+	}
+
+	var comments int
+	if err = testDb.Get(&comments, "select comments from t_contest where tcid = 1001"); err != nil {
+		t.Fatal(err)
+	}
+	if comments != 1 {
+		t.Errorf("CreateForumPostForMatch() t_contest comments = %v, want 1", comments)
+	}
+
+	// a redelivered creation has to update the existing post instead of creating a second one
+	threadId2, postId2, err := d.CreateForumPostForMatch(ctx, matchInfo, "renamed", PostText{Pagetext: "[i]second[/i] text", Htmltext: "<i>second</i> text"}, 0)
+	if err != nil {
+		t.Fatalf("CreateForumPostForMatch() second error = %v", err)
+	}
+	if threadId2 != threadId || postId2 != postId {
+		t.Errorf("CreateForumPostForMatch() second threadId = %v, postId = %v, want %v, %v", threadId2, postId2, threadId, postId)
+	}
+
+	if post = getTestPost(t, postId); post.Htmltext != "<i>second</i> text" || post.Pagetext != "[i]second[/i] text" {
+		t.Errorf("CreateForumPostForMatch() second htmltext = %v, pagetext = %v", post.Htmltext, post.Pagetext)
+	}
+
+	var posts int
+	if err = testDb.Get(&posts, "select count(*) from forum_post where threadid = ?", threadId); err != nil {
+		t.Fatal(err)
+	}
+	if posts != 1 {
+		t.Errorf("CreateForumPostForMatch() second posts = %v, want 1", posts)
+	}
+
+	if thread = getTestThread(t, threadId); thread.Title != "renamed" || thread.Replies != 1 {
+		t.Errorf("CreateForumPostForMatch() second thread = %+v, want title renamed and 1 reply", thread)
+	}
+
+	if err = testDb.Get(&comments, "select comments from t_contest where tcid = 1001"); err != nil {
+		t.Fatal(err)
+	}
+	if comments != 1 {
+		t.Errorf("CreateForumPostForMatch() second t_contest comments = %v, want 1", comments)
+	}
+
+	var threads int
+	if err = testDb.Get(&threads, "select count(*) from forum_thread where ext_id = ?", matchInfo.MsID); err != nil {
+		t.Fatal(err)
+	}
+	if threads != 1 {
+		t.Errorf("CreateForumPostForMatch() threads = %v, want 1", threads)
+	}
+}
+
+func TestDotlanDbClientImpl_CreateForumPostForMatch_resultPost(t *testing.T) {
+	d := newTestClient()
+	ctx := context.Background()
+
+	matchInfo := &matchservice.MatchInfo{MsID: "1901", MatchTitle: "result post"}
+	threadId, postId, err := d.CreateForumPostForMatch(ctx, matchInfo, matchInfo.MatchTitle, testPostText("post"), 0)
+	if err != nil {
+		t.Fatalf("CreateForumPostForMatch() error = %v", err)
+	}
+	resultPostId, err := d.CloseForumThreadForMatch(ctx, matchInfo, threadId, postId, testPostText("result"), false)
+	if err != nil {
+		t.Fatalf("CloseForumThreadForMatch() error = %v", err)
+	}
+	if _, err = testDb.Exec("delete from forum_post where postid = ?", postId); err != nil {
+		t.Fatal(err)
+	}
+
+	// the post deleted by an admin is created again, the result post is never reused
+	_, newPostId, err := d.CreateForumPostForMatch(ctx, matchInfo, matchInfo.MatchTitle, testPostText("recreated"), resultPostId)
+	if err != nil {
+		t.Fatalf("CreateForumPostForMatch() error = %v", err)
+	}
+	if newPostId == postId || newPostId == resultPostId {
+		t.Errorf("CreateForumPostForMatch() postId = %v, want a new post", newPostId)
+	}
+	if post := getTestPost(t, resultPostId); post.Htmltext != "result" {
+		t.Errorf("CreateForumPostForMatch() result post htmltext = %v, want unchanged", post.Htmltext)
+	}
+}
+
+func TestDotlanDbClientImpl_UpdateForumPostForMatch(t *testing.T) {
+	d := newTestClient()
+	ctx := context.Background()
+
+	threadId, postId, err := d.CreateForumPostForMatch(ctx, &matchservice.MatchInfo{MsID: "1002"}, "update", testPostText("initial"), 0)
+	if err != nil {
+		t.Fatalf("CreateForumPostForMatch() error = %v", err)
+	}
+
+	postDateline := time.Date(2022, 5, 1, 18, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		postId      int
+		text        string
+		wantHistory int
+		wantErr     error
+	}{
+		{
+			name:        "ok-existing_post",
+			postId:      postId,
+			text:        "updated",
+			wantHistory: 1,
+		},
+		{
+			name:        "ok-unchanged_text",
+			postId:      postId,
+			text:        "updated",
+			wantHistory: 2,
+		},
+		{
+			name:    "err-missing_post",
+			postId:  999999,
+			text:    "updated",
+			wantErr: ErrForumPostNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// simulate stale thread metadata, which has to be repaired from the newest post by the update
+			if _, err := testDb.Exec("update forum_thread set lastposter = '', lastposttime = null, replies = 0 where threadid = ?", threadId); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := testDb.Exec("update forum_post set dateline = ? where postid = ?", postDateline, postId); err != nil {
+				t.Fatal(err)
+			}
+
+			err := d.UpdateForumPostForMatch(ctx, tt.postId, testPostText(tt.text))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("UpdateForumPostForMatch() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr == nil {
+				if post := getTestPost(t, tt.postId); post.Htmltext != tt.text {
+					t.Errorf("UpdateForumPostForMatch() htmltext = %v, want %v", post.Htmltext, tt.text)
+				}
+				var history string
+				if err = testDb.Get(&history, "select history from forum_post where postid = ?", tt.postId); err != nil {
+					t.Fatal(err)
+				}
+				if entries, err := parseSerializedArray(history); err != nil || len(entries) != tt.wantHistory {
+					t.Errorf("UpdateForumPostForMatch() history entries = %v, error = %v, want %v", len(entries), err, tt.wantHistory)
+				}
+				// the edit must not bump the thread, the last post time is the dateline of the newest post
+				if thread := getTestThread(t, threadId); thread.Lastposter != testUserNick || thread.Replies != 1 || !thread.Lastposttime.Equal(postDateline) {
+					t.Errorf("UpdateForumPostForMatch() lastposter = %v, lastposttime = %v, replies = %v, want %v, %v, 1", thread.Lastposter, thread.Lastposttime, thread.Replies, testUserNick, postDateline)
+				}
+			}
+		})
+	}
+}
+
+func TestDotlanDbClientImpl_withTx(t *testing.T) {
+	d := newTestClient()
+	ctx := context.Background()
+
+	errFailed := errors.New("failed")
+
+	err := d.withTx(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, "insert into t_contest (tcid, comments) values (2001, 0)")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("withTx() error = %v", err)
+	}
+
+	var count int
+	if err = testDb.Get(&count, "select count(*) from t_contest where tcid = 2001"); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("withTx() committed rows = %v, want 1", count)
+	}
+
+	err = d.withTx(ctx, func(tx *sqlx.Tx) error {
+		return errFailed
+	})
+	if !errors.Is(err, errFailed) {
+		t.Errorf("withTx() error = %v, want %v", err, errFailed)
+	}
+}
+
+func TestDotlanDbClientImpl_CloseForumThreadForMatch(t *testing.T) {
+	d := newTestClient()
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		matchId     string
+		resultText  string
+		lockPost    bool
+		missing     bool
+		wantReplies int
+		wantErr     error
+	}{
+		{
+			name:        "ok-result_and_lock",
+			matchId:     "1101",
+			resultText:  "cool-team won",
+			lockPost:    true,
+			wantReplies: 2,
+		},
+		{
+			name:        "ok-without_result",
+			matchId:     "1102",
+			wantReplies: 1,
+		},
+		{
+			name:    "err-missing_thread",
+			matchId: "1103",
+			missing: true,
+			wantErr: ErrForumThreadNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matchInfo := &matchservice.MatchInfo{MsID: tt.matchId, MatchTitle: tt.name}
+
+			threadId, postId, err := d.CreateForumPostForMatch(ctx, matchInfo, matchInfo.MatchTitle, testPostText("post"), 0)
+			if err != nil {
+				t.Fatalf("CreateForumPostForMatch() error = %v", err)
+			}
+			if tt.missing {
+				if _, err = testDb.Exec("delete from forum_thread where threadid = ?", threadId); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			resultPostId, err := d.CloseForumThreadForMatch(ctx, matchInfo, threadId, postId, testPostText(tt.resultText), tt.lockPost)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CloseForumThreadForMatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			thread := getTestThread(t, threadId)
+			if !thread.Closed || thread.Replies != tt.wantReplies {
+				t.Errorf("CloseForumThreadForMatch() closed = %v, replies = %v, want true, %v", thread.Closed, thread.Replies, tt.wantReplies)
+			}
+
+			if post := getTestPost(t, postId); post.Locked != tt.lockPost {
+				t.Errorf("CloseForumThreadForMatch() locked = %v, want %v", post.Locked, tt.lockPost)
+			}
+
+			if tt.resultText == "" {
+				if resultPostId != 0 {
+					t.Errorf("CloseForumThreadForMatch() resultPostId = %v, want 0", resultPostId)
+				}
+				return
+			}
+
+			resultPost := getTestPost(t, resultPostId)
+			if int(resultPost.Threadid) != threadId || resultPost.Htmltext != tt.resultText {
+				t.Errorf("CloseForumThreadForMatch() result post = %+v", resultPost)
+			}
+		})
+	}
+}
+
+func TestDotlanDbClientImpl_CreateForumPostForMatch_unknownUser(t *testing.T) {
+	d := newTestClient()
+	d.config = testConfigClient{config: &config.Config{
+		Config: unwindiaConfig.Config{
+			CmsConfig: unwindiaConfig.CmsConfig{UserId: 999},
+		},
+	}}
+
+	_, _, err := d.CreateForumPostForMatch(context.Background(), &matchservice.MatchInfo{MsID: "1003"}, "unknown user", testPostText("text"), 0)
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("CreateForumPostForMatch() error = %v, want %v", err, ErrUserNotFound)
+	}
+
+	var threads int
+	if err = testDb.Get(&threads, "select count(*) from forum_thread where ext_id = '1003'"); err != nil {
+		t.Fatal(err)
+	}
+	if threads != 0 {
+		t.Errorf("CreateForumPostForMatch() threads = %v, want 0", threads)
+	}
+}
+
+func TestDotlanDbClientImpl_UpdateForumThreadTitle(t *testing.T) {
+	d := newTestClient()
+	ctx := context.Background()
+
+	threadId, _, err := d.CreateForumPostForMatch(ctx, &matchservice.MatchInfo{MsID: "1004"}, "old title", testPostText("text"), 0)
+	if err != nil {
+		t.Fatalf("CreateForumPostForMatch() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		threadId int
+		title    string
+		wantErr  error
+	}{
+		{
+			name:     "ok-changed_title",
+			threadId: threadId,
+			title:    "new title",
+		},
+		{
+			name:     "ok-unchanged_title",
+			threadId: threadId,
+			title:    "new title",
+		},
+		{
+			name:     "err-missing_thread",
+			threadId: 999999,
+			title:    "new title",
+			wantErr:  ErrForumThreadNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := d.UpdateForumThreadTitle(ctx, tt.threadId, tt.title)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("UpdateForumThreadTitle() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr == nil {
+				if thread := getTestThread(t, tt.threadId); thread.Title != tt.title {
+					t.Errorf("UpdateForumThreadTitle() title = %v, want %v", thread.Title, tt.title)
+				}
+			}
+		})
+	}
+}
+
+func TestDotlanDbClientImpl_CreateForumPostForMatch_forumRouting(t *testing.T) {
+	d := newTestClient()
+	d.config = testConfigClient{config: &config.Config{
+		Config: unwindiaConfig.Config{
+			CmsConfig: unwindiaConfig.CmsConfig{UserId: testUserId, DefaultGame: "csgo"},
+		},
+		ForumRouting: routing.Table{
+			{Game: "csgo", ForumID: 10},
+			{Game: "lol", Tournament: "LoL 5on5", ForumID: 20},
+		},
+	}}
+
+	tests := []struct {
+		name        string
+		matchInfo   *matchservice.MatchInfo
+		wantForumId int
+	}{
+		{
+			name:        "ok-routed_by_game",
+			matchInfo:   &matchservice.MatchInfo{MsID: "1201", Game: "csgo"},
+			wantForumId: 10,
+		},
+		{
+			name:        "ok-routed_by_default_game",
+			matchInfo:   &matchservice.MatchInfo{MsID: "1202"},
+			wantForumId: 10,
+		},
+		{
+			name:        "ok-routed_by_game_and_tournament",
+			matchInfo:   &matchservice.MatchInfo{MsID: "1203", Game: "lol", TournamentName: "LoL 5on5"},
+			wantForumId: 20,
+		},
+		{
+			name:        "ok-fallback_env",
+			matchInfo:   &matchservice.MatchInfo{MsID: "1204", Game: "lol", TournamentName: "LoL 1on1"},
+			wantForumId: testForumId,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			threadId, _, err := d.CreateForumPostForMatch(context.Background(), tt.matchInfo, tt.name, testPostText("text"), 0)
+			if err != nil {
+				t.Fatalf("CreateForumPostForMatch() error = %v", err)
+			}
+
+			if thread := getTestThread(t, threadId); thread.Forumid != tt.wantForumId {
+				t.Errorf("CreateForumPostForMatch() forumid = %v, want %v", thread.Forumid, tt.wantForumId)
+			}
+		})
+	}
+}
+
+func TestDotlanDbClientImpl_ArchiveForumThread(t *testing.T) {
+	d := newTestClient()
+	ctx := context.Background()
+
+	threadId, _, err := d.CreateForumPostForMatch(ctx, &matchservice.MatchInfo{MsID: "1301"}, "archive", testPostText("text"), 0)
+	if err != nil {
+		t.Fatalf("CreateForumPostForMatch() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		threadId int
+		wantErr  error
+	}{
+		{
+			name:     "ok-existing_thread",
+			threadId: threadId,
+		},
+		{
+			name:     "ok-already_archived",
+			threadId: threadId,
+		},
+		{
+			name:     "err-missing_thread",
+			threadId: 999999,
+			wantErr:  ErrForumThreadNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := d.ArchiveForumThread(ctx, tt.threadId, 99)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ArchiveForumThread() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr == nil {
+				if thread := getTestThread(t, tt.threadId); thread.Forumid != 99 || thread.ShowLatest {
+					t.Errorf("ArchiveForumThread() forumid = %v, show_latest = %v, want 99, false", thread.Forumid, thread.ShowLatest)
+				}
+			}
+		})
+	}
+}
+
+func TestDotlanDbClientImpl_GetThreadForumId(t *testing.T) {
+	d := newTestClient()
+	ctx := context.Background()
+
+	threadId, _, err := d.CreateForumPostForMatch(ctx, &matchservice.MatchInfo{MsID: "1701"}, "forum", testPostText("text"), 0)
+	if err != nil {
+		t.Fatalf("CreateForumPostForMatch() error = %v", err)
+	}
+	if err = d.ArchiveForumThread(ctx, threadId, 98); err != nil {
+		t.Fatalf("ArchiveForumThread() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		matchId string
+		want    int
+		wantErr error
+	}{
+		{
+			name:    "ok-moved_thread",
+			matchId: "1701",
+			want:    98,
+		},
+		{
+			name:    "err-no_thread",
+			matchId: "1799",
+			wantErr: ErrForumThreadNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := d.GetThreadForumId(ctx, tt.matchId)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetThreadForumId() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetThreadForumId() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDotlanDbClientImpl_GetMatchData(t *testing.T) {
+	d := newTestClient()
+	ctx := context.Background()
+
+	for _, stmt := range []string{
+		"INSERT INTO t_contest (tcid, tid, tcrunde, tcrow, team_a, team_b, starttime) VALUES (1401, 5, 2, 3, 11, 12, '2022-10-29 14:00:00')",
+		"INSERT INTO t_contest (tcid, tid, tcrunde, tcrow, team_a, team_b) VALUES (1402, 5, 1, 1, 11, 0)",
+		"INSERT INTO t_teilnehmer (tnid, tid, name) VALUES (11, 5, 'cool-team'), (12, 5, 'nice-team')",
+		"INSERT INTO user (id, nick) VALUES (21, 'alice'), (22, 'bob'), (23, 'carol')",
+		"INSERT INTO t_teilnehmer_part (tnid, user_id) VALUES (11, 22), (11, 21), (12, 23)",
+	} {
+		if _, err := testDb.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		tcid        string
+		wantRound   int
+		wantTeam1   []TeamMember
+		wantTeam2   []TeamMember
+		wantNoTeam2 bool
+		wantErr     error
+	}{
+		{
+			name:      "ok-both_teams",
+			tcid:      "1401",
+			wantRound: 2,
+			wantTeam1: []TeamMember{{UserId: 21, Nick: "alice"}, {UserId: 22, Nick: "bob"}},
+			wantTeam2: []TeamMember{{UserId: 23, Nick: "carol"}},
+		},
+		{
+			name:        "ok-team_not_set",
+			tcid:        "1402",
+			wantRound:   1,
+			wantTeam1:   []TeamMember{{UserId: 21, Nick: "alice"}, {UserId: 22, Nick: "bob"}},
+			wantNoTeam2: true,
+		},
+		{
+			name:    "err-unknown_contest",
+			tcid:    "1499",
+			wantErr: ErrContestNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := d.GetMatchData(ctx, tt.tcid)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetMatchData() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if got.Contest.Round != tt.wantRound || got.Contest.Tid != 5 {
+				t.Errorf("GetMatchData() contest = %+v", got.Contest)
+			}
+			if got.Team1 == nil || got.Team1.Name != "cool-team" || !reflect.DeepEqual(got.Team1.Members, tt.wantTeam1) {
+				t.Errorf("GetMatchData() team1 = %+v, want members %+v", got.Team1, tt.wantTeam1)
+			}
+			if tt.wantNoTeam2 {
+				if got.Team2 != nil {
+					t.Errorf("GetMatchData() team2 = %+v, want nil", got.Team2)
+				}
+				return
+			}
+			if got.Team2 == nil || got.Team2.Name != "nice-team" || !reflect.DeepEqual(got.Team2.Members, tt.wantTeam2) {
+				t.Errorf("GetMatchData() team2 = %+v, want members %+v", got.Team2, tt.wantTeam2)
+			}
+		})
+	}
+}
+
+func TestDotlanDbClientImpl_ListForumReplies(t *testing.T) {
+	d := newTestClient()
+	ctx := context.Background()
+
+	threadId, postId, err := d.CreateForumPostForMatch(ctx, &matchservice.MatchInfo{MsID: "1501"}, "replies", testPostText("text"), 0)
+	if err != nil {
+		t.Fatalf("CreateForumPostForMatch() error = %v", err)
+	}
+
+	for _, stmt := range []string{
+		"INSERT INTO user (id, nick) VALUES (31, 'dave')",
+		fmt.Sprintf("INSERT INTO forum_post (threadid, userid, dateline, pagetext, htmltext) VALUES (%d, 31, '2022-10-29 14:00:00', '[b]ready[/b]', '<b>ready</b>')", threadId),
+		fmt.Sprintf("INSERT INTO forum_post (threadid, userid, dateline, pagetext, htmltext) VALUES (%d, %d, '2022-10-29 14:01:00', 'bot', 'bot')", threadId, testUserId),
+		fmt.Sprintf("INSERT INTO forum_post (threadid, userid, dateline, pagetext, htmltext) VALUES (%d, 32, '2022-10-29 14:02:00', 'gg', 'gg')", threadId),
+	} {
+		if _, err := testDb.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		threadId  int
+		after     int
+		wantNicks []string
+		wantTexts []string
+	}{
+		{
+			name:      "ok-all_replies",
+			threadId:  threadId,
+			after:     postId,
+			wantNicks: []string{"dave", ""},
+			wantTexts: []string{"[b]ready[/b]", "gg"},
+		},
+		{
+			name:      "ok-after_first_reply",
+			threadId:  threadId,
+			after:     postId + 1,
+			wantNicks: []string{""},
+			wantTexts: []string{"gg"},
+		},
+		{
+			name:      "ok-missing_thread",
+			threadId:  999999,
+			wantNicks: []string{},
+			wantTexts: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := d.ListForumReplies(ctx, tt.threadId, tt.after)
+			if err != nil {
+				t.Fatalf("ListForumReplies() error = %v", err)
+			}
+
+			nicks, texts := []string{}, []string{}
+			for _, reply := range got {
+				nicks = append(nicks, reply.Nick)
+				texts = append(texts, reply.Pagetext)
+			}
+			if !reflect.DeepEqual(nicks, tt.wantNicks) || !reflect.DeepEqual(texts, tt.wantTexts) {
+				t.Errorf("ListForumReplies() nicks = %v, texts = %v, want %v, %v", nicks, texts, tt.wantNicks, tt.wantTexts)
+			}
+		})
+	}
+}
+
+func TestMatchData_TeamOfUser(t *testing.T) {
+	data := &MatchData{
+		Team1: &Team{Tnid: 11, Members: []TeamMember{{UserId: 21}, {UserId: 22}}},
+		Team2: &Team{Tnid: 12, Members: []TeamMember{{UserId: 23}}},
+	}
+
+	tests := []struct {
+		name         string
+		data         *MatchData
+		userId       uint
+		wantTnid     uint
+		wantPosition int
+	}{
+		{
+			name:         "ok-team1",
+			data:         data,
+			userId:       22,
+			wantTnid:     11,
+			wantPosition: 1,
+		},
+		{
+			name:         "ok-team2",
+			data:         data,
+			userId:       23,
+			wantTnid:     12,
+			wantPosition: 2,
+		},
+		{
+			name:   "ok-no_member",
+			data:   data,
+			userId: 31,
+		},
+		{
+			name:   "ok-teams_not_set",
+			data:   &MatchData{},
+			userId: 21,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			team, position := tt.data.TeamOfUser(tt.userId)
+			tnid := uint(0)
+			if team != nil {
+				tnid = team.Tnid
+			}
+			if tnid != tt.wantTnid || position != tt.wantPosition {
+				t.Errorf("TeamOfUser() tnid = %v, position = %v, want %v, %v", tnid, position, tt.wantTnid, tt.wantPosition)
+			}
+		})
+	}
+}
+
+func TestDotlanDbClientImpl_ListContests(t *testing.T) {
+	d := newTestClient()
+	ctx := context.Background()
+
+	if _, err := testDb.Exec("INSERT INTO t_contest (tcid, tid, team_a, team_b) VALUES (1601, 7, 11, 12)"); err != nil {
+		t.Fatal(err)
+	}
+
+	getHash := func() string {
+		contests, err := d.ListContests(ctx)
+		if err != nil {
+			t.Fatalf("ListContests() error = %v", err)
+		}
+		for _, contest := range contests {
+			if contest.Tcid == 1601 {
+				return contest.SyncHash()
+			}
+		}
+		t.Fatalf("ListContests() did not return contest 1601")
+		return ""
+	}
+	hash := getHash()
+
+	tests := []struct {
+		name       string
+		stmt       string
+		wantChange bool
+	}{
+		{
+			name: "ok-comments_ignored",
+			stmt: "UPDATE t_contest SET comments = comments + 1 WHERE tcid = 1601",
+		},
+		{
+			name:       "ok-result_changed",
+			stmt:       "UPDATE t_contest SET won = 11 WHERE tcid = 1601",
+			wantChange: true,
+		},
+		{
+			name:       "ok-starttime_changed",
+			stmt:       "UPDATE t_contest SET starttime = '2022-10-29 15:00:00' WHERE tcid = 1601",
+			wantChange: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := testDb.Exec(tt.stmt); err != nil {
+				t.Fatal(err)
+			}
+			newHash := getHash()
+			if (newHash != hash) != tt.wantChange {
+				t.Errorf("SyncHash() changed = %v, want %v", newHash != hash, tt.wantChange)
+			}
+			hash = newHash
+		})
+	}
+}
+
+func TestDotlanDbClientImpl_ListContests_tournamentFilter(t *testing.T) {
+	ctx := context.Background()
+
+	stmts := []string{
+		"INSERT INTO t_turnier (tid, teventid, tgameserver) VALUES (81, 1, 1), (82, 1, 0), (83, 2, 1)",
+		"INSERT INTO t_contest (tcid, tid) VALUES (1801, 81), (1802, 82), (1803, 83)",
+	}
+	for _, stmt := range stmts {
+		if _, err := testDb.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter string
+		want   []uint
+	}{
+		{
+			name:   "ok-filtered",
+			filter: "teventid = 1 and tgameserver = 1",
+			want:   []uint{1801},
+		},
+		{
+			name:   "ok-no_filter",
+			filter: "",
+			want:   []uint{1801, 1802, 1803},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestClient()
+			d.config = testConfigClient{config: &config.Config{
+				Config: unwindiaConfig.Config{
+					CmsConfig: unwindiaConfig.CmsConfig{UserId: testUserId, TournamentFilter: tt.filter},
+				},
+			}}
+
+			contests, err := d.ListContests(ctx)
+			if err != nil {
+				t.Fatalf("ListContests() error = %v", err)
+			}
+
+			var got []uint
+			for _, contest := range contests {
+				if contest.Tcid >= 1801 && contest.Tcid <= 1803 {
+					got = append(got, contest.Tcid)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListContests() contests = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDotlanDbClientImpl_SendPrivateMessages(t *testing.T) {
+	d := newTestClient()
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		userIds []uint
+		subject string
+	}{
+		{
+			name:    "ok-two_recipients",
+			userIds: []uint{41, 42},
+			subject: "server credentials",
+		},
+		{
+			name:    "ok-no_recipients",
+			subject: "nobody",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := d.SendPrivateMessages(ctx, tt.userIds, tt.subject, "password: secret"); err != nil {
+				t.Fatalf("SendPrivateMessages() error = %v", err)
+			}
+
+			var messages []PrivateMessage
+			if err := testDb.Select(&messages, "select from_id, to_id, subject, text, unread from user_pm where subject = ? order by to_id", tt.subject); err != nil {
+				t.Fatal(err)
+			}
+			if len(messages) != len(tt.userIds) {
+				t.Fatalf("SendPrivateMessages() sent %d messages, want %d", len(messages), len(tt.userIds))
+			}
+			for i, message := range messages {
+				if message.FromId != testUserId || message.ToId != tt.userIds[i] || message.Text != "password: secret" || !message.Unread {
+					t.Errorf("SendPrivateMessages() persisted message = %+v", message)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchData_UserIds(t *testing.T) {
+	tests := []struct {
+		name string
+		data *MatchData
+		want []uint
+	}{
+		{
+			name: "ok-both_teams",
+			data: &MatchData{
+				Team1: &Team{Members: []TeamMember{{UserId: 22}, {UserId: 21}}},
+				Team2: &Team{Members: []TeamMember{{UserId: 23}, {UserId: 22}}},
+			},
+			want: []uint{21, 22, 23},
+		},
+		{
+			name: "ok-teams_not_set",
+			data: &MatchData{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.data.UserIds(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UserIds() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
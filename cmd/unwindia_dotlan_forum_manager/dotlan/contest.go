@@ -0,0 +1,198 @@
+package dotlan
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrContestNotFound is returned if the contest of a match does not exist within dotlan
+	ErrContestNotFound = errors.New("contest not found")
+)
+
+// MatchData holds the records of a match read from dotlan, which are passed to the templates as .Dotlan
+type MatchData struct {
+	Contest *Contest
+	Team1   *Team
+	Team2   *Team
+}
+
+// UserIds returns the dotlan user ids of the members of both teams in ascending order
+func (m *MatchData) UserIds() []uint {
+	seen := make(map[uint]bool)
+	var userIds []uint
+	for _, team := range []*Team{m.Team1, m.Team2} {
+		if team == nil {
+			continue
+		}
+		for _, member := range team.Members {
+			if !seen[member.UserId] {
+				seen[member.UserId] = true
+				userIds = append(userIds, member.UserId)
+			}
+		}
+	}
+
+	sort.Slice(userIds, func(i, j int) bool { return userIds[i] < userIds[j] })
+	return userIds
+}
+
+// TeamOfUser returns the team the user is member of together with its position 1 or 2 within the match. If the user
+// is member of none of the teams, nil and 0 are returned.
+func (m *MatchData) TeamOfUser(userId uint) (*Team, int) {
+	for i, team := range []*Team{m.Team1, m.Team2} {
+		if team == nil {
+			continue
+		}
+		for _, member := range team.Members {
+			if member.UserId == userId {
+				return team, i + 1
+			}
+		}
+	}
+
+	return nil, 0
+}
+
+// GetMatchData reads the contest with the given tcid and its teams with their members from dotlan. Teams which are not
+// set yet are nil.
+func (d *DotlanDbClientImpl) GetMatchData(ctx context.Context, tcid string) (*MatchData, error) {
+	defer prometheus.NewTimer(metrics.MySQLQueryDuration.WithLabelValues("get_match_data")).ObserveDuration()
+
+	contest, err := d.getContest(ctx, tcid)
+	if err != nil {
+		return nil, err
+	}
+
+	data := MatchData{Contest: contest}
+
+	if data.Team1, err = d.getTeam(ctx, contest.TeamA); err != nil {
+		return nil, err
+	}
+	if data.Team2, err = d.getTeam(ctx, contest.TeamB); err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+// ListContests reads all contests from dotlan, ordered by tcid. Only contests of tournaments matching the tournament
+// filter of the config are returned.
+func (d *DotlanDbClientImpl) ListContests(ctx context.Context) ([]Contest, error) {
+	defer prometheus.NewTimer(metrics.MySQLQueryDuration.WithLabelValues("list_contests")).ObserveDuration()
+
+	fields, err := d.getFieldsFromModelWithTablename(Contest{}, Contest{}.TableName())
+	if err != nil {
+		return nil, err
+	}
+	if fields == "" {
+		return nil, fmt.Errorf("no known columns in table %s", Contest{}.TableName())
+	}
+
+	// the tournament filter is a condition on the tournament table taken from the config, e.g. teventid = 1
+	where := ""
+	if filter := strings.TrimSpace(d.config.GetConfig().CmsConfig.TournamentFilter); filter != "" {
+		where = fmt.Sprintf("where tid in (select tid from %s where %s) ", Tournament{}.TableName(), filter)
+	}
+
+	contests := []Contest{}
+	qry := fmt.Sprintf("select %s from %s %sorder by tcid", fields, Contest{}.TableName(), where)
+	log.Trace().Str("query", qry).Msg("prepared query for listing contests")
+
+	if err = d.db.SelectContext(ctx, &contests, qry); err != nil {
+		log.Error().Err(err).Msg("error listing contests")
+		return nil, err
+	}
+
+	return contests, nil
+}
+
+// SyncHash returns a hash of the contest fields which are synced to Unwindia. The comments counter is excluded, as it
+// is increased by the forum manager itself.
+func (c *Contest) SyncHash() string {
+	starttime := ""
+	if c.Starttime.Valid {
+		starttime = c.Starttime.Time.UTC().Format(time.RFC3339)
+	}
+
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%d|%d|%d|%d|%d|%d|%d|%d|%d|%s",
+		c.Tcid, c.Tid, c.Round, c.Position, c.TeamA, c.TeamB, c.WinsA, c.WinsB, c.Won, starttime)))
+
+	return hex.EncodeToString(hash[:])
+}
+
+func (d *DotlanDbClientImpl) getContest(ctx context.Context, tcid string) (*Contest, error) {
+	fields, err := d.getFieldsFromModelWithTablename(Contest{}, Contest{}.TableName())
+	if err != nil {
+		return nil, err
+	}
+	if fields == "" {
+		return nil, fmt.Errorf("no known columns in table %s", Contest{}.TableName())
+	}
+
+	var contest Contest
+	qry := fmt.Sprintf("select %s from %s where tcid = ?", fields, Contest{}.TableName())
+	log.Debug().Str("query", qry).Str("tcid", tcid).Msg("prepared query for getting contest")
+
+	err = d.db.GetContext(ctx, &contest, qry, tcid)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrContestNotFound, tcid)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("error getting contest")
+		return nil, err
+	}
+
+	return &contest, nil
+}
+
+// getTeam reads the team with the given id and its members. It returns nil if the id is 0.
+func (d *DotlanDbClientImpl) getTeam(ctx context.Context, tnid uint) (*Team, error) {
+	if tnid == 0 {
+		return nil, nil
+	}
+
+	fields, err := d.getFieldsFromModelWithTablename(Team{}, Team{}.TableName())
+	if err != nil {
+		return nil, err
+	}
+	if fields == "" {
+		return nil, fmt.Errorf("no known columns in table %s", Team{}.TableName())
+	}
+
+	var team Team
+	qry := fmt.Sprintf("select %s from %s where tnid = ?", fields, Team{}.TableName())
+	log.Debug().Str("query", qry).Uint("tnid", tnid).Msg("prepared query for getting team")
+
+	err = d.db.GetContext(ctx, &team, qry, tnid)
+	if err == sql.ErrNoRows {
+		// the team was deleted, the contest is rendered without it
+		log.Warn().Uint("tnid", tnid).Msg("team of contest not found")
+		return nil, nil
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("error getting team")
+		return nil, err
+	}
+
+	qry = fmt.Sprintf("select p.user_id, u.nick from %s p join `%s` u on u.id = p.user_id where p.tnid = ? order by u.nick",
+		TeamMember{}.TableName(), User{}.TableName())
+	log.Debug().Str("query", qry).Uint("tnid", tnid).Msg("prepared query for getting team members")
+
+	if err = d.db.SelectContext(ctx, &team.Members, qry, tnid); err != nil {
+		log.Error().Err(err).Msg("error getting team members")
+		return nil, err
+	}
+
+	return &team, nil
+}
@@ -0,0 +1,195 @@
+package dotlan
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// errInvalidHistory is returned if the history of a post is no PHP serialized array
+	errInvalidHistory = errors.New("invalid post history")
+)
+
+// HistoryEntry is a single edit of a forum post
+type HistoryEntry struct {
+	UserId   uint
+	Nick     string
+	Dateline time.Time
+}
+
+// serialize returns the entry as PHP serialized array:
+// a:3:{s:6:"userid";i:<id>;s:4:"nick";s:<len>:"<nick>";s:8:"dateline";i:<unix time>;}
+// The layout of the edits written by dotlan itself is not known, so appendHistory never interprets existing entries.
+func (h HistoryEntry) serialize() string {
+	return fmt.Sprintf("a:3:{%s%s%s%s%s%s}",
+		serializeString("userid"), serializeInt(int64(h.UserId)),
+		serializeString("nick"), serializeString(h.Nick),
+		serializeString("dateline"), serializeInt(h.Dateline.Unix()),
+	)
+}
+
+// serializedElement is a key value pair of a PHP serialized array, both in their serialized form
+type serializedElement struct {
+	key   string
+	value string
+}
+
+// appendHistory appends the entry to the history of a post, which dotlan stores as PHP serialized array of edits. The
+// existing elements are kept byte for byte with their keys, whatever their layout, so entries written by the dotlan
+// editor itself are preserved. The entry is added with the next free integer key, like PHP's $history[] = $entry.
+func appendHistory(history string, entry HistoryEntry) (string, error) {
+	var elements []serializedElement
+
+	if strings.TrimSpace(history) != "" {
+		var err error
+		elements, err = parseSerializedArray(history)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	nextKey := int64(0)
+	for _, element := range elements {
+		if !strings.HasPrefix(element.key, "i:") {
+			continue
+		}
+		key, err := strconv.ParseInt(strings.TrimSuffix(element.key[2:], ";"), 10, 64)
+		if err == nil && key >= nextKey {
+			nextKey = key + 1
+		}
+	}
+	elements = append(elements, serializedElement{key: serializeInt(nextKey), value: entry.serialize()})
+
+	var builder strings.Builder
+	builder.WriteString("a:" + strconv.Itoa(len(elements)) + ":{")
+	for _, element := range elements {
+		builder.WriteString(element.key)
+		builder.WriteString(element.value)
+	}
+	builder.WriteString("}")
+
+	return builder.String(), nil
+}
+
+// parseSerializedArray returns the elements of a PHP serialized array in order
+func parseSerializedArray(serialized string) ([]serializedElement, error) {
+	if !strings.HasPrefix(serialized, "a:") {
+		return nil, errInvalidHistory
+	}
+
+	end, err := skipValue(serialized, 0)
+	if err != nil {
+		return nil, err
+	}
+	if end != len(serialized) {
+		return nil, fmt.Errorf("%w: trailing data at %d", errInvalidHistory, end)
+	}
+
+	count, pos, err := readLength(serialized, 2, ':')
+	if err != nil {
+		return nil, err
+	}
+	pos++ // skip {
+
+	elements := make([]serializedElement, 0, count)
+	for i := 0; i < count; i++ {
+		keyEnd, err := skipValue(serialized, pos)
+		if err != nil {
+			return nil, err
+		}
+
+		valueEnd, err := skipValue(serialized, keyEnd)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, serializedElement{key: serialized[pos:keyEnd], value: serialized[keyEnd:valueEnd]})
+		pos = valueEnd
+	}
+
+	return elements, nil
+}
+
+// skipValue returns the position after the PHP serialized value starting at pos
+func skipValue(serialized string, pos int) (int, error) {
+	if pos >= len(serialized) {
+		return 0, fmt.Errorf("%w: unexpected end at %d", errInvalidHistory, pos)
+	}
+
+	switch serialized[pos] {
+	case 'N':
+		if pos+1 < len(serialized) && serialized[pos+1] == ';' {
+			return pos + 2, nil
+		}
+	case 'i', 'b', 'd':
+		if pos+1 >= len(serialized) || serialized[pos+1] != ':' {
+			break
+		}
+		end := strings.IndexByte(serialized[pos:], ';')
+		if end < 0 {
+			break
+		}
+		return pos + end + 1, nil
+	case 's':
+		length, start, err := readLength(serialized, pos+2, ':')
+		if err != nil {
+			return 0, err
+		}
+		// s:<length>:"<bytes>";
+		end := start + 1 + length
+		if start >= len(serialized) || serialized[start] != '"' || end+2 > len(serialized) || serialized[end:end+2] != "\";" {
+			break
+		}
+		return end + 2, nil
+	case 'a':
+		count, start, err := readLength(serialized, pos+2, ':')
+		if err != nil {
+			return 0, err
+		}
+		if start >= len(serialized) || serialized[start] != '{' {
+			break
+		}
+		pos = start + 1
+		for i := 0; i < count*2; i++ {
+			if pos, err = skipValue(serialized, pos); err != nil {
+				return 0, err
+			}
+		}
+		if pos >= len(serialized) || serialized[pos] != '}' {
+			break
+		}
+		return pos + 1, nil
+	}
+
+	return 0, fmt.Errorf("%w: unexpected value at %d", errInvalidHistory, pos)
+}
+
+// readLength reads the decimal number starting at pos, which has to be followed by the separator. It returns the
+// number and the position after the separator.
+func readLength(serialized string, pos int, separator byte) (int, int, error) {
+	if pos > len(serialized) {
+		return 0, 0, fmt.Errorf("%w: unexpected end at %d", errInvalidHistory, pos)
+	}
+
+	end := strings.IndexByte(serialized[pos:], separator)
+	if end < 0 {
+		return 0, 0, fmt.Errorf("%w: missing separator after %d", errInvalidHistory, pos)
+	}
+
+	length, err := strconv.Atoi(serialized[pos : pos+end])
+	if err != nil || length < 0 {
+		return 0, 0, fmt.Errorf("%w: invalid length at %d", errInvalidHistory, pos)
+	}
+
+	return length, pos + end + 1, nil
+}
+
+func serializeInt(i int64) string {
+	return "i:" + strconv.FormatInt(i, 10) + ";"
+}
+
+func serializeString(s string) string {
+	return "s:" + strconv.Itoa(len(s)) + ":\"" + s + "\";"
+}
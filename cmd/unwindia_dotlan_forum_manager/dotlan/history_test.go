@@ -0,0 +1,132 @@
+package dotlan
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var testHistoryEntry = HistoryEntry{
+	UserId:   3,
+	Nick:     "unwindia",
+	Dateline: time.Unix(1666000000, 0),
+}
+
+const serializedTestHistoryEntry = `a:3:{s:6:"userid";i:3;s:4:"nick";s:8:"unwindia";s:8:"dateline";i:1666000000;}`
+
+const serializedMultibyteHistoryEntry = `a:3:{s:6:"userid";i:7;s:4:"nick";s:6:"Ädmin";s:8:"dateline";i:1665000000;}`
+
+// No history written by a real dotlan install is available, so the layout of dotlan's own edits is unknown. The
+// foreign entries therefore deliberately differ from the entries of the bot: other keys, key order and value types, a
+// nested array and an object. appendHistory has to keep all of them byte for byte.
+const (
+	foreignHistoryEntry       = `a:4:{s:4:"time";s:10:"1665000000";s:4:"user";s:6:"Ädmin";s:2:"id";i:7;s:6:"reason";N;}`
+	foreignNestedHistoryEntry = `a:2:{s:4:"edit";a:2:{i:0;i:7;i:1;d:1665000000.5;}s:6:"hidden";b:1;}`
+	foreignObjectHistoryEntry = `O:8:"stdClass":1:{s:4:"user";i:7;}`
+)
+
+func TestHistoryEntry_serialize(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry HistoryEntry
+		want  string
+	}{
+		{
+			name:  "ok-bot_entry",
+			entry: testHistoryEntry,
+			want:  serializedTestHistoryEntry,
+		},
+		{
+			name:  "ok-multibyte_nick",
+			entry: HistoryEntry{UserId: 7, Nick: "Ädmin", Dateline: time.Unix(1665000000, 0)},
+			want:  serializedMultibyteHistoryEntry,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.entry.serialize(); got != tt.want {
+				t.Errorf("serialize() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_appendHistory(t *testing.T) {
+	tests := []struct {
+		name    string
+		history string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "ok-empty_history",
+			history: "",
+			want:    `a:1:{i:0;` + serializedTestHistoryEntry + `}`,
+		},
+		{
+			name:    "ok-append_own_entry",
+			history: `a:1:{i:0;` + serializedTestHistoryEntry + `}`,
+			want:    `a:2:{i:0;` + serializedTestHistoryEntry + `i:1;` + serializedTestHistoryEntry + `}`,
+		},
+		{
+			name:    "ok-keep_foreign_entries",
+			history: `a:2:{i:0;` + foreignHistoryEntry + `i:1;` + foreignNestedHistoryEntry + `}`,
+			want:    `a:3:{i:0;` + foreignHistoryEntry + `i:1;` + foreignNestedHistoryEntry + `i:2;` + serializedTestHistoryEntry + `}`,
+		},
+		{
+			name:    "ok-keep_foreign_keys",
+			history: `a:2:{i:5;` + foreignHistoryEntry + `s:4:"last";` + foreignNestedHistoryEntry + `}`,
+			want:    `a:3:{i:5;` + foreignHistoryEntry + `s:4:"last";` + foreignNestedHistoryEntry + `i:6;` + serializedTestHistoryEntry + `}`,
+		},
+		{
+			name:    "ok-string_keys_only",
+			history: `a:1:{s:4:"last";` + foreignHistoryEntry + `}`,
+			want:    `a:2:{s:4:"last";` + foreignHistoryEntry + `i:0;` + serializedTestHistoryEntry + `}`,
+		},
+		{
+			name:    "err-unsupported_object",
+			history: `a:1:{i:0;` + foreignObjectHistoryEntry + `}`,
+			wantErr: true,
+		},
+		{
+			name:    "ok-string_containing_delimiters",
+			history: `a:1:{i:0;s:7:"a;}"b:1";}`,
+			want:    `a:2:{i:0;s:7:"a;}"b:1";i:1;` + serializedTestHistoryEntry + `}`,
+		},
+		{
+			name:    "err-no_array",
+			history: `edited by admin`,
+			wantErr: true,
+		},
+		{
+			name:    "err-truncated",
+			history: `a:2:{i:0;s:4:"test";`,
+			wantErr: true,
+		},
+		{
+			name:    "err-wrong_string_length",
+			history: `a:1:{i:0;s:10:"test";}`,
+			wantErr: true,
+		},
+		{
+			name:    "err-trailing_data",
+			history: `a:0:{}a:0:{}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := appendHistory(tt.history, testHistoryEntry)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("appendHistory() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil && !errors.Is(err, errInvalidHistory) {
+				t.Errorf("appendHistory() error = %v, want %v", err, errInvalidHistory)
+			}
+			if got != tt.want {
+				t.Errorf("appendHistory() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package dotlan
 
 import (
+	"gopkg.in/guregu/null.v4"
 	"time"
 )
 
@@ -47,3 +48,100 @@ type ForumPost struct {
 func (ForumPost) TableName() string {
 	return "forum_post"
 }
+
+type User struct {
+	Id   uint   `db:"id"`
+	Nick string `db:"nick"`
+}
+
+func (User) TableName() string {
+	return "user"
+}
+
+// Tournament is a dotlan tournament, which CmsConfig.TournamentFilter is applied to
+type Tournament struct {
+	Tid uint `db:"tid"`
+}
+
+func (Tournament) TableName() string {
+	return "t_turnier"
+}
+
+// Contest is a single match of a dotlan tournament
+type Contest struct {
+	Tcid      uint      `db:"tcid"`
+	Tid       uint      `db:"tid"`
+	Round     int       `db:"tcrunde"`
+	Position  int       `db:"tcrow"`
+	TeamA     uint      `db:"team_a"`
+	TeamB     uint      `db:"team_b"`
+	WinsA     int       `db:"wins_a"`
+	WinsB     int       `db:"wins_b"`
+	Won       int       `db:"won"`
+	Starttime null.Time `db:"starttime"`
+	Comments  int       `db:"comments"`
+}
+
+func (Contest) TableName() string {
+	return "t_contest"
+}
+
+// Team is a participant of a dotlan tournament
+type Team struct {
+	Tnid    uint         `db:"tnid"`
+	Tid     uint         `db:"tid"`
+	Name    string       `db:"name"`
+	Members []TeamMember `db:"-"`
+}
+
+func (Team) TableName() string {
+	return "t_teilnehmer"
+}
+
+// TeamMember is a dotlan user which is member of a team
+type TeamMember struct {
+	UserId uint   `db:"user_id"`
+	Nick   string `db:"nick"`
+}
+
+func (TeamMember) TableName() string {
+	return "t_teilnehmer_part"
+}
+
+// ForumReply is a post of a dotlan user within a forum thread together with the nick of the user
+type ForumReply struct {
+	Postid   int       `db:"postid"`
+	Threadid int       `db:"threadid"`
+	Userid   uint      `db:"userid"`
+	Nick     string    `db:"nick"`
+	Dateline time.Time `db:"dateline"`
+	Pagetext string    `db:"pagetext"`
+	Htmltext string    `db:"htmltext"`
+}
+
+// PrivateMessage is a private message sent from one dotlan user to another
+type PrivateMessage struct {
+	Id       int       `db:"id"`
+	FromId   uint      `db:"from_id"`
+	ToId     uint      `db:"to_id"`
+	Subject  string    `db:"subject"`
+	Text     string    `db:"text"`
+	Dateline time.Time `db:"dateline"`
+	Unread   bool      `db:"unread"`
+}
+
+func (PrivateMessage) TableName() string {
+	return "user_pm"
+}
+
+// PostText is the text of a forum post, stored as BBCode source (pagetext), which the dotlan editor loads, and as
+// rendered html (htmltext)
+type PostText struct {
+	Pagetext string
+	Htmltext string
+}
+
+// IsEmpty returns true if the post has no text
+func (p PostText) IsEmpty() bool {
+	return p.Pagetext == "" && p.Htmltext == ""
+}
@@ -0,0 +1,45 @@
+package dotlan
+
+import (
+	"context"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/metrics"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+// SendPrivateMessages sends a private message of the bot user to every given user. All messages are inserted with a
+// single statement, so either all or none of them are sent.
+func (d *DotlanDbClientImpl) SendPrivateMessages(ctx context.Context, userIds []uint, subject, text string) error {
+	defer prometheus.NewTimer(metrics.MySQLQueryDuration.WithLabelValues("send_private_messages")).ObserveDuration()
+
+	if len(userIds) == 0 {
+		return nil
+	}
+
+	fromId := d.config.GetConfig().CmsConfig.UserId
+	now := time.Now()
+
+	insert := sq.Insert(PrivateMessage{}.TableName()).
+		Columns("from_id", "to_id", "subject", "text", "dateline", "unread")
+	for _, userId := range userIds {
+		insert = insert.Values(fromId, userId, subject, text, now, 1)
+	}
+
+	qry, args, err := insert.ToSql()
+	if err != nil {
+		log.Error().Err(err).Msg("error creating private messages sql")
+		return err
+	}
+	// the args hold the message text, which contains the credentials of the match
+	log.Debug().Str("query", qry).Int("recipients", len(userIds)).Msg("create private messages query")
+
+	if _, err = d.db.ExecContext(ctx, qry, args...); err != nil {
+		log.Error().Err(err).Msg("error sending private messages")
+		return err
+	}
+
+	metrics.ForumWrites.WithLabelValues(metrics.OperationPMSent).Add(float64(len(userIds)))
+	return nil
+}
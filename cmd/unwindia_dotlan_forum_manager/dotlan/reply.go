@@ -0,0 +1,27 @@
+package dotlan
+
+import (
+	"context"
+	"fmt"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+)
+
+func (d *DotlanDbClientImpl) ListForumReplies(ctx context.Context, threadId, afterPostId int) ([]ForumReply, error) {
+	defer prometheus.NewTimer(metrics.MySQLQueryDuration.WithLabelValues("list_forum_replies")).ObserveDuration()
+
+	qry := fmt.Sprintf("select p.postid, p.threadid, p.userid, coalesce(u.nick, '') as nick, p.dateline, coalesce(p.pagetext, '') as pagetext, coalesce(p.htmltext, '') as htmltext "+
+		"from %s p left join `%s` u on u.id = p.userid "+
+		"where p.threadid = ? and p.postid > ? and p.userid <> ? order by p.postid",
+		ForumPost{}.TableName(), User{}.TableName())
+	log.Trace().Str("query", qry).Int("threadId", threadId).Int("afterPostId", afterPostId).Msg("prepared query for listing replies")
+
+	replies := []ForumReply{}
+	if err := d.db.SelectContext(ctx, &replies, qry, threadId, afterPostId, d.config.GetConfig().CmsConfig.UserId); err != nil {
+		log.Error().Err(err).Int("threadId", threadId).Msg("error listing replies")
+		return nil, err
+	}
+
+	return replies, nil
+}
@@ -0,0 +1,52 @@
+package dotlan
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+)
+
+// TxError is returned if a transaction failed and rolling it back failed as well
+type TxError struct {
+	// Err is the error which caused the rollback
+	Err error
+	// RollbackErr is the error returned by the rollback
+	RollbackErr error
+}
+
+func (e *TxError) Error() string {
+	return fmt.Sprintf("%v (rollback failed: %v)", e.Err, e.RollbackErr)
+}
+
+func (e *TxError) Unwrap() error {
+	return e.Err
+}
+
+// withTx runs fn within a transaction. The transaction is committed if fn succeeds and rolled back if fn returns an
+// error or panics. If the rollback fails as well, both errors are returned as TxError.
+func (d *DotlanDbClientImpl) withTx(ctx context.Context, fn func(tx *sqlx.Tx) error) (err error) {
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error beginning transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return &TxError{Err: err, RollbackErr: rollbackErr}
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return nil
+}
@@ -2,7 +2,6 @@ package dotlan
 
 import (
 	"errors"
-	"fmt"
 	"github.com/GSH-LAN/Unwindia_common/src/go/helper"
 	"github.com/GSH-LAN/Unwindia_common/src/go/sql"
 	"github.com/rs/zerolog/log"
@@ -20,6 +19,8 @@ func (d *DotlanDbClientImpl) getFieldsFromModelWithoutTablename(model sql.Table)
 }
 
 func (d *DotlanDbClientImpl) getFieldsFromModelWithTablename(model interface{}, tableName string) (string, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
 
 	if queryString, ok := d.modelFieldCache[tableName]; ok {
 		log.Debug().Str("table", tableName).Str("query", queryString).Msg("Retrieved query from cache")
@@ -29,9 +30,9 @@ func (d *DotlanDbClientImpl) getFieldsFromModelWithTablename(model interface{},
 	var columns []string
 	var queryString string
 
-	qry := fmt.Sprintf("SELECT column_name FROM information_schema.columns WHERE table_name = '%s'", tableName)
-	log.Trace().Str("query", qry).Msgf("getFieldsFromModelWithTablename")
-	if err := d.db.Select(&columns, qry); err != nil {
+	qry := "SELECT column_name FROM information_schema.columns WHERE table_schema = database() AND table_name = ?"
+	log.Trace().Str("query", qry).Str("table", tableName).Msgf("getFieldsFromModelWithTablename")
+	if err := d.db.Select(&columns, qry, tableName); err != nil {
 		return "", err
 	}
 
@@ -42,9 +43,9 @@ func (d *DotlanDbClientImpl) getFieldsFromModelWithTablename(model interface{},
 		if val, ok := typeField.Tag.Lookup("db"); ok {
 			if helper.StringSliceContains(columns, val) {
 				if len(queryString) > 0 {
-					queryString = queryString + ", " + val
+					queryString = queryString + ", `" + val + "`"
 				} else {
-					queryString = val
+					queryString = "`" + val + "`"
 				}
 			}
 		}
@@ -10,6 +10,14 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/segmentio/ksuid"
 	"runtime"
+	"time"
+)
+
+const (
+	// CredentialsDeliveryForum renders the server credentials into the forum post of the match
+	CredentialsDeliveryForum = "forum"
+	// CredentialsDeliveryPM sends the server credentials as dotlan private message to the members of both teams
+	CredentialsDeliveryPM = "pm"
 )
 
 var (
@@ -21,12 +29,32 @@ type environment struct {
 	environment2.BaseEnvironment
 
 	ServiceUid                 string
-	DotlanMySQLHost            string `env:"MYSQL_HOST"`
-	DotlanMySQLPort            int    `env:"MYSQL_PORT" envDefault:"3306"`
-	DotlanMySQLUser            string `env:"MYSQL_USER"`
-	DotlanMySQLPassword        string `env:"MYSQL_PASSWORD"`
-	DotlanMySQLDatabase        string `env:"MYSQL_DATABASE"`
-	DotlanContestForumThreadId int    `env:"DOTLAN_CONTEST_FORUM_THREAD_ID" envDefault:"9"`
+	DotlanMySQLHost            string   `env:"MYSQL_HOST"`
+	DotlanMySQLPort            int      `env:"MYSQL_PORT" envDefault:"3306"`
+	DotlanMySQLUser            string   `env:"MYSQL_USER"`
+	DotlanMySQLPassword        string   `env:"MYSQL_PASSWORD"`
+	DotlanMySQLDatabase        string   `env:"MYSQL_DATABASE"`
+	DotlanContestForumThreadId int      `env:"DOTLAN_CONTEST_FORUM_THREAD_ID" envDefault:"9"`
+	DotlanLockPostOnFinish     bool     `env:"DOTLAN_LOCK_POST_ON_FINISH" envDefault:"false" envDescription:"Lock the forum post of a match in addition to closing its thread when the match is finished"`
+	DotlanArchiveForumId       int      `env:"DOTLAN_ARCHIVE_FORUM_ID" envDefault:"0" envDescription:"Forum to which the threads of finished matches are moved, 0 disables archiving"`
+	DotlanPrivateForumIds      []int    `env:"DOTLAN_PRIVATE_FORUM_IDS" envSeparator:"," envDescription:"Forums whose templates receive the values of sensitive MatchInfo fields"`
+	TemplateSensitiveFields    []string `env:"TEMPLATE_SENSITIVE_FIELDS" envDefault:"ServerPasswordMgmt" envSeparator:"," envDescription:"MatchInfo fields which are masked in the templates of forums which are not private"`
+	DotlanCredentialsDelivery  string   `env:"DOTLAN_CREDENTIALS_DELIVERY" envDefault:"forum" envDescription:"Delivery of the server credentials, forum renders them into the forum post, pm sends them as private message to the team members"`
+
+	PulsarNackRedeliveryDelay time.Duration `env:"PULSAR_NACK_REDELIVERY_DELAY" envDefault:"30s" envDescription:"Delay after which a failed message is delivered again"`
+	PulsarMaxRedeliveries     uint32        `env:"PULSAR_MAX_REDELIVERIES" envDefault:"10" envDescription:"Maximum amount of redeliveries of a failed message before it is moved to the dead letter topic"`
+	PulsarDeadLetterTopic     string        `env:"PULSAR_DEAD_LETTER_TOPIC" envDefault:"UNWINDIA_DOTLAN_FORUM_MANAGER_DLQ"`
+	PulsarMatchCommentTopic   string        `env:"PULSAR_MATCH_COMMENT_TOPIC" envDefault:"UNWINDIA_MATCH_COMMENT" envDescription:"Topic on which replies to match threads are published"`
+
+	ProcessInterval     time.Duration `env:"PROCESS_INTERVAL" envDefault:"10s" envDescription:"Interval of reconciling the stored forum states with the dotlan forum, 0 disables reconciling"`
+	ReplyPollInterval   time.Duration `env:"REPLY_POLL_INTERVAL" envDefault:"30s" envDescription:"Interval of polling the match threads for new replies, which are published on the message queue, 0 disables polling"`
+	ContestSyncInterval time.Duration `env:"DOTLAN_CONTEST_SYNC_INTERVAL" envDefault:"0s" envDescription:"Interval of polling dotlan for new or changed contests, which are published as matches on the message queue, 0 disables the sync"`
+
+	AdminHTTPPort int    `env:"ADMIN_HTTP_PORT" envDefault:"8081" envDescription:"Port of the admin api, which is served separately from the probes and metrics"`
+	AdminToken    string `env:"ADMIN_TOKEN" json:"-" envDescription:"Bearer token required by every request of the admin api, the admin api is disabled if empty"`
+
+	MatchDebounceWindow   time.Duration `env:"MATCH_DEBOUNCE_WINDOW" envDefault:"0s" envDescription:"Window in which multiple events of the same match are coalesced into one forum update, 0 disables coalescing"`
+	MatchDebounceMaxDelay time.Duration `env:"MATCH_DEBOUNCE_MAX_DELAY" envDefault:"10s" envDescription:"Maximum delay of a match event by coalescing"`
 }
 
 // Environment holds all environment configuration with more advanced typing and validation
@@ -54,6 +82,10 @@ func load() *Environment {
 		e.ServiceUid = ksuid.New().String()
 	}
 
+	if e.DotlanCredentialsDelivery != CredentialsDeliveryForum && e.DotlanCredentialsDelivery != CredentialsDeliveryPM {
+		log.Panic().Str("delivery", e.DotlanCredentialsDelivery).Msg("Invalid DOTLAN_CREDENTIALS_DELIVERY, must be forum or pm")
+	}
+
 	var pulsarAuthParams = make(map[string]string)
 	if e.PulsarAuthParams != "" {
 		if err := json.Unmarshal([]byte(e.PulsarAuthParams), &pulsarAuthParams); err != nil {
@@ -2,7 +2,8 @@ package main
 
 import (
 	"context"
-	"github.com/GSH-LAN/Unwindia_common/src/go/config"
+	unwindiaConfig "github.com/GSH-LAN/Unwindia_common/src/go/config"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/config"
 	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/environment"
 	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/server"
 	"github.com/gammazero/workerpool"
@@ -27,7 +28,11 @@ func main() {
 	if env.ConfigFileName != "" {
 		configClient, err = config.NewConfigFile(mainContext, env.ConfigFileName, env.ConfigTemplatesDir)
 	} else {
-		configClient, err = config.NewConfigClient()
+		var unwindiaConfigClient unwindiaConfig.ConfigClient
+		unwindiaConfigClient, err = unwindiaConfig.NewConfigClient()
+		if err == nil {
+			configClient = config.FromUnwindiaConfigClient(unwindiaConfigClient)
+		}
 	}
 
 	if err != nil {
@@ -0,0 +1,214 @@
+package messagequeue
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/metrics"
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/rs/zerolog/log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	PropertyFailureReason     = "failureReason"
+	PropertyAttempts          = "attempts"
+	PropertyOriginalTopic     = "originalTopic"
+	PropertyOriginalMessageId = "originalMessageId"
+	PropertyReplayedFrom      = "replayedFrom"
+
+	// deadLetterSubscription is the durable subscription of the admin api on the dead letter topic, which tracks the
+	// replayed dead letters
+	deadLetterSubscription = SubscriberName + "_DLQ_ADMIN"
+	// deadLetterReceiveTimeout is the time after which reading the dead letter topic stops if no more letter arrives
+	deadLetterReceiveTimeout = 2 * time.Second
+)
+
+// DeadLetter is a message of the dead letter topic
+type DeadLetter struct {
+	ID                string    `json:"id"`
+	PublishTime       time.Time `json:"publishTime"`
+	FailureReason     string    `json:"failureReason"`
+	Attempts          int       `json:"attempts"`
+	OriginalMessageId string    `json:"originalMessageId"`
+	Payload           string    `json:"payload"`
+}
+
+// DeadLetterQueue moves messages which could not be processed to a dead letter topic and replays them onto the main
+// topic on demand
+type DeadLetterQueue struct {
+	client             pulsar.Client
+	topic              string
+	deadLetterTopic    string
+	deadLetterProducer pulsar.Producer
+	replayProducer     pulsar.Producer
+	// readLock serializes the reads of the dead letter topic, since the exclusive subscription admits one consumer only
+	readLock sync.Mutex
+}
+
+func NewDeadLetterQueue(client pulsar.Client, topic, deadLetterTopic string) (*DeadLetterQueue, error) {
+	deadLetterProducer, err := client.CreateProducer(pulsar.ProducerOptions{
+		Topic: fmt.Sprintf(topicBase, deadLetterTopic),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	replayProducer, err := client.CreateProducer(pulsar.ProducerOptions{
+		Topic: fmt.Sprintf(topicBase, topic),
+	})
+	if err != nil {
+		deadLetterProducer.Close()
+		return nil, err
+	}
+
+	return &DeadLetterQueue{
+		client:             client,
+		topic:              topic,
+		deadLetterTopic:    deadLetterTopic,
+		deadLetterProducer: deadLetterProducer,
+		replayProducer:     replayProducer,
+	}, nil
+}
+
+// Publish moves the message to the dead letter topic, carrying the failure reason and the amount of processing
+// attempts as properties
+func (d *DeadLetterQueue) Publish(ctx context.Context, msg pulsar.Message, reason string, attempts uint32) error {
+	properties := make(map[string]string, len(msg.Properties())+4)
+	for key, value := range msg.Properties() {
+		properties[key] = value
+	}
+	properties[PropertyFailureReason] = reason
+	properties[PropertyAttempts] = strconv.FormatUint(uint64(attempts), 10)
+	properties[PropertyOriginalTopic] = msg.Topic()
+	properties[PropertyOriginalMessageId] = encodeMessageId(msg.ID())
+
+	_, err := d.deadLetterProducer.Send(ctx, &pulsar.ProducerMessage{
+		Payload:    msg.Payload(),
+		Key:        msg.Key(),
+		Properties: properties,
+	})
+	if err != nil {
+		return err
+	}
+
+	metrics.MessagesDeadLettered.Inc()
+	log.Warn().Str("topic", d.deadLetterTopic).Str("reason", reason).Uint32("attempts", attempts).Msg("Moved message to dead letter topic")
+
+	return nil
+}
+
+// List returns all messages of the dead letter topic which were not replayed yet
+func (d *DeadLetterQueue) List(ctx context.Context) ([]DeadLetter, error) {
+	deadLetters := []DeadLetter{}
+
+	err := d.read(ctx, func(msg pulsar.Message) (bool, error) {
+		deadLetters = append(deadLetters, toDeadLetter(msg))
+		return false, nil
+	})
+
+	return deadLetters, err
+}
+
+// Replay publishes the dead letters with the given ids onto the main topic again. All dead letters are replayed if no
+// ids are given. Every replayed letter is acknowledged, so it is neither listed nor replayed again. It returns the
+// amount of replayed messages.
+func (d *DeadLetterQueue) Replay(ctx context.Context, ids []string) (int, error) {
+	selected := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		selected[id] = true
+	}
+
+	replayed := 0
+	err := d.read(ctx, func(msg pulsar.Message) (bool, error) {
+		id := encodeMessageId(msg.ID())
+		if len(selected) > 0 && !selected[id] {
+			return false, nil
+		}
+
+		_, err := d.replayProducer.Send(ctx, &pulsar.ProducerMessage{
+			Payload:    msg.Payload(),
+			Key:        msg.Key(),
+			Properties: map[string]string{PropertyReplayedFrom: id},
+		})
+		if err != nil {
+			return false, err
+		}
+
+		replayed++
+		log.Info().Str("topic", d.topic).Str("deadLetterId", id).Msg("Replayed dead letter")
+		return true, nil
+	})
+
+	return replayed, err
+}
+
+func (d *DeadLetterQueue) Close() {
+	d.deadLetterProducer.Close()
+	d.replayProducer.Close()
+}
+
+// read calls fn for every dead letter which was not acknowledged yet. The topic is read through a durable subscription,
+// so letters for which fn returns true are acknowledged and skipped by all later reads, while all other letters are
+// delivered again on the next read. Reading stops once no letter arrives within deadLetterReceiveTimeout. Concurrent
+// reads wait for each other.
+func (d *DeadLetterQueue) read(ctx context.Context, fn func(msg pulsar.Message) (bool, error)) error {
+	d.readLock.Lock()
+	defer d.readLock.Unlock()
+
+	consumer, err := d.client.Subscribe(pulsar.ConsumerOptions{
+		Topic:                       fmt.Sprintf(topicBase, d.deadLetterTopic),
+		SubscriptionName:            deadLetterSubscription,
+		Type:                        pulsar.Exclusive,
+		SubscriptionInitialPosition: pulsar.SubscriptionPositionEarliest,
+	})
+	if err != nil {
+		return err
+	}
+	// unacknowledged letters are redelivered to the next consumer of the subscription
+	defer consumer.Close()
+
+	for {
+		receiveCtx, cancel := context.WithTimeout(ctx, deadLetterReceiveTimeout)
+		msg, err := consumer.Receive(receiveCtx)
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		ack, err := fn(msg)
+		if err != nil {
+			return err
+		}
+
+		if ack {
+			if err = consumer.Ack(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toDeadLetter(msg pulsar.Message) DeadLetter {
+	attempts, _ := strconv.Atoi(msg.Properties()[PropertyAttempts])
+
+	return DeadLetter{
+		ID:                encodeMessageId(msg.ID()),
+		PublishTime:       msg.PublishTime(),
+		FailureReason:     msg.Properties()[PropertyFailureReason],
+		Attempts:          attempts,
+		OriginalMessageId: msg.Properties()[PropertyOriginalMessageId],
+		Payload:           string(msg.Payload()),
+	}
+}
+
+// encodeMessageId returns the serialized message id in a form which can be passed through the admin api
+func encodeMessageId(id pulsar.MessageID) string {
+	return base64.RawURLEncoding.EncodeToString(id.Serialize())
+}
@@ -0,0 +1,79 @@
+package messagequeue
+
+import (
+	"context"
+	"errors"
+	"github.com/apache/pulsar-client-go/pulsar"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testExclusiveClient admits one consumer of the dead letter subscription at a time like pulsar does for exclusive
+// subscriptions, all other methods are not implemented
+type testExclusiveClient struct {
+	pulsar.Client
+	lock      sync.Mutex
+	consumers int
+}
+
+func (t *testExclusiveClient) Subscribe(_ pulsar.ConsumerOptions) (pulsar.Consumer, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.consumers > 0 {
+		return nil, errors.New("consumer busy")
+	}
+	t.consumers++
+	return &testEmptyConsumer{client: t}, nil
+}
+
+// testEmptyConsumer is a consumer of an empty topic
+type testEmptyConsumer struct {
+	pulsar.Consumer
+	client *testExclusiveClient
+}
+
+func (t *testEmptyConsumer) Receive(ctx context.Context) (pulsar.Message, error) {
+	// keep the subscription busy for a while, so concurrent reads overlap
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(20 * time.Millisecond):
+		return nil, context.DeadlineExceeded
+	}
+}
+
+func (t *testEmptyConsumer) Close() {
+	t.client.lock.Lock()
+	defer t.client.lock.Unlock()
+	t.client.consumers--
+}
+
+func TestDeadLetterQueue_concurrentReads(t *testing.T) {
+	d := &DeadLetterQueue{client: &testExclusiveClient{}, deadLetterTopic: "test"}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 2; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := d.List(context.Background())
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := d.Replay(context.Background(), nil)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("concurrent List() and Replay() error = %v, want nil", err)
+		}
+	}
+}
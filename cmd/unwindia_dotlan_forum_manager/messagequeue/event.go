@@ -0,0 +1,38 @@
+package messagequeue
+
+import (
+	"github.com/GSH-LAN/Unwindia_common/src/go/matchservice"
+	"github.com/GSH-LAN/Unwindia_common/src/go/messagebroker"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+const (
+	// MetadataFailureReason is the metadata key of a message holding the reason of its negative acknowledgement
+	MetadataFailureReason = "failureReason"
+	// MetadataDeadLetter marks a message which has to be moved to the dead letter topic without being redelivered
+	MetadataDeadLetter = "deadLetter"
+)
+
+// MatchEvent is a match message received from the message queue together with its subtype
+type MatchEvent struct {
+	SubType   messagebroker.MatchEvent
+	MatchInfo *matchservice.MatchInfo
+	message   *message.Message
+}
+
+// Ack acknowledges the underlying message after it was processed successfully, so it will not be delivered again
+func (e *MatchEvent) Ack() {
+	if e.message != nil {
+		e.message.Ack()
+	}
+}
+
+// Nack negatively acknowledges the underlying message after its processing failed, so it gets redelivered
+func (e *MatchEvent) Nack(reason error) {
+	if e.message != nil {
+		if reason != nil {
+			e.message.Metadata.Set(MetadataFailureReason, reason.Error())
+		}
+		e.message.Nack()
+	}
+}
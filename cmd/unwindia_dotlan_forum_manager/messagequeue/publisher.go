@@ -0,0 +1,137 @@
+package messagequeue
+
+import (
+	"context"
+	"fmt"
+	"github.com/GSH-LAN/Unwindia_common/src/go/matchservice"
+	"github.com/GSH-LAN/Unwindia_common/src/go/messagebroker"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/metrics"
+	"github.com/apache/pulsar-client-go/pulsar"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+const (
+	// MatchCommentSubType is the subtype of messages carrying a reply to the forum thread of a match
+	MatchCommentSubType = "UNWINDIA_MATCH_COMMENT"
+	// MatchCommandSubType is the subtype of messages carrying a chat command of a team member
+	MatchCommandSubType = "UNWINDIA_MATCH_COMMAND"
+	// PropertySource is the pulsar message property naming the service which published the message
+	PropertySource = "source"
+)
+
+// MatchComment is a reply of a dotlan user to the forum thread of a match
+type MatchComment struct {
+	MatchID   string    `json:"matchId"`
+	ThreadID  int       `json:"threadId"`
+	PostID    int       `json:"postId"`
+	UserID    uint      `json:"userId"`
+	Nick      string    `json:"nick"`
+	Text      string    `json:"text"`
+	HTML      string    `json:"html"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// MatchCommand is a chat command written by a member of one of the teams into the forum thread of a match
+type MatchCommand struct {
+	MatchID   string    `json:"matchId"`
+	PostID    int       `json:"postId"`
+	UserID    uint      `json:"userId"`
+	Nick      string    `json:"nick"`
+	TeamID    uint      `json:"teamId"`
+	Team      int       `json:"team"`
+	Command   string    `json:"command"`
+	Argument  string    `json:"argument,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Publisher publishes messages of the forum manager for other Unwindia services. Every message carries the source
+// property, so the subscriber skips the match messages published by the forum manager itself.
+type Publisher struct {
+	commentProducer pulsar.Producer
+	matchProducer   pulsar.Producer
+}
+
+func NewPublisher(client pulsar.Client, commentTopic string) (*Publisher, error) {
+	commentProducer, err := client.CreateProducer(pulsar.ProducerOptions{
+		Topic: fmt.Sprintf(topicBase, commentTopic),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	matchProducer, err := client.CreateProducer(pulsar.ProducerOptions{
+		Topic: fmt.Sprintf(topicBase, messagebroker.TOPIC),
+	})
+	if err != nil {
+		commentProducer.Close()
+		return nil, err
+	}
+
+	return &Publisher{
+		commentProducer: commentProducer,
+		matchProducer:   matchProducer,
+	}, nil
+}
+
+// PublishMatchComment publishes the reply as message with subtype UNWINDIA_MATCH_COMMENT, keyed by the match id so
+// the replies of a match are consumed in order
+func (p *Publisher) PublishMatchComment(ctx context.Context, comment *MatchComment) error {
+	if err := p.publish(ctx, p.commentProducer, comment.MatchID, messagebroker.MessageTypeCreated, MatchCommentSubType, comment); err != nil {
+		return err
+	}
+
+	metrics.MatchCommentsPublished.Inc()
+	log.Debug().Str("topic", p.commentProducer.Topic()).Str("matchId", comment.MatchID).Int("postId", comment.PostID).Msg("Published match comment")
+
+	return nil
+}
+
+// PublishMatchCommand publishes the command as message with subtype UNWINDIA_MATCH_COMMAND, keyed by the match id
+func (p *Publisher) PublishMatchCommand(ctx context.Context, command *MatchCommand) error {
+	if err := p.publish(ctx, p.commentProducer, command.MatchID, messagebroker.MessageTypeCreated, MatchCommandSubType, command); err != nil {
+		return err
+	}
+
+	metrics.MatchCommands.WithLabelValues(command.Command, metrics.CommandPublished).Inc()
+	log.Debug().Str("topic", p.commentProducer.Topic()).Str("matchId", command.MatchID).Str("command", command.Command).Msg("Published match command")
+
+	return nil
+}
+
+// PublishMatch publishes the match on the Unwindia match topic, keyed by its MatchService id
+func (p *Publisher) PublishMatch(ctx context.Context, messageType messagebroker.MessageTypes, event messagebroker.MatchEvent, match *matchservice.MatchInfo) error {
+	if err := p.publish(ctx, p.matchProducer, match.MsID, messageType, event.String(), match); err != nil {
+		return err
+	}
+
+	metrics.MatchesPublished.WithLabelValues(event.String()).Inc()
+	log.Debug().Str("topic", p.matchProducer.Topic()).Str("matchId", match.MsID).Str("subType", event.String()).Msg("Published match")
+
+	return nil
+}
+
+func (p *Publisher) publish(ctx context.Context, producer pulsar.Producer, key string, messageType messagebroker.MessageTypes, subType string, data interface{}) error {
+	payload, err := jsoniter.Marshal(messagebroker.Message{
+		Type:    messageType,
+		SubType: subType,
+		Data:    data,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = producer.Send(ctx, &pulsar.ProducerMessage{
+		Payload:    payload,
+		Key:        key,
+		Properties: map[string]string{PropertySource: SubscriberName},
+	})
+
+	return err
+}
+
+func (p *Publisher) Close() {
+	p.commentProducer.Close()
+	p.matchProducer.Close()
+}
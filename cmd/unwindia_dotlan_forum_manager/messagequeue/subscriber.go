@@ -6,6 +6,7 @@ import (
 	"github.com/GSH-LAN/Unwindia_common/src/go/matchservice"
 	"github.com/GSH-LAN/Unwindia_common/src/go/messagebroker"
 	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/environment"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/metrics"
 	"github.com/ThreeDotsLabs/watermill/message"
 	"github.com/apache/pulsar-client-go/pulsar"
 	jsoniter "github.com/json-iterator/go"
@@ -19,14 +20,17 @@ const (
 )
 
 type Subscriber struct {
-	mainContext    context.Context
-	pulsarClient   pulsar.Client
-	pulsarConsumer pulsar.Consumer
-	topic          string
-	matchInfoChan  chan<- *matchservice.MatchInfo
+	mainContext     context.Context
+	pulsarClient    pulsar.Client
+	pulsarConsumer  pulsar.Consumer
+	topic           string
+	maxRedeliveries uint32
+	deadLetterQueue *DeadLetterQueue
+	publisher       *Publisher
+	matchEventChan  chan<- *MatchEvent
 }
 
-func NewSubscriber(ctx context.Context, env *environment.Environment, matchInfoChan chan *matchservice.MatchInfo) (*Subscriber, error) {
+func NewSubscriber(ctx context.Context, env *environment.Environment, matchEventChan chan *MatchEvent) (*Subscriber, error) {
 	client, err := pulsar.NewClient(pulsar.ClientOptions{
 		URL:            env.PulsarURL,
 		Authentication: env.PulsarAuth,
@@ -36,62 +40,114 @@ func NewSubscriber(ctx context.Context, env *environment.Environment, matchInfoC
 	}
 
 	consumer, err := client.Subscribe(pulsar.ConsumerOptions{
-		Topic:            fmt.Sprintf(topicBase, messagebroker.TOPIC),
-		SubscriptionName: SubscriberName,
-		Type:             pulsar.Shared,
+		Topic:               fmt.Sprintf(topicBase, messagebroker.TOPIC),
+		SubscriptionName:    SubscriberName,
+		Type:                pulsar.Shared,
+		NackRedeliveryDelay: env.PulsarNackRedeliveryDelay,
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
+	deadLetterQueue, err := NewDeadLetterQueue(client, messagebroker.TOPIC, env.PulsarDeadLetterTopic)
+	if err != nil {
+		return nil, err
+	}
+
+	publisher, err := NewPublisher(client, env.PulsarMatchCommentTopic)
+	if err != nil {
+		return nil, err
+	}
+
 	subscriber := Subscriber{
-		mainContext:    ctx,
-		topic:          messagebroker.TOPIC,
-		pulsarClient:   client,
-		pulsarConsumer: consumer,
-		matchInfoChan:  matchInfoChan,
+		mainContext:     ctx,
+		topic:           messagebroker.TOPIC,
+		pulsarClient:    client,
+		pulsarConsumer:  consumer,
+		maxRedeliveries: env.PulsarMaxRedeliveries,
+		deadLetterQueue: deadLetterQueue,
+		publisher:       publisher,
+		matchEventChan:  matchEventChan,
 	}
 
 	return &subscriber, nil
 }
 
+// DeadLetterQueue returns the queue of messages which could not be processed
+func (s *Subscriber) DeadLetterQueue() *DeadLetterQueue {
+	return s.deadLetterQueue
+}
+
+// Publisher returns the publisher for messages of the forum manager
+func (s *Subscriber) Publisher() *Publisher {
+	return s.publisher
+}
+
+// Ping checks the connection to the pulsar broker by looking up the partitions of the subscribed topic
+func (s *Subscriber) Ping(ctx context.Context) error {
+	_, err := s.pulsarClient.TopicPartitions(fmt.Sprintf(topicBase, s.topic))
+	return err
+}
+
 func (s *Subscriber) processMessages(messages <-chan *message.Message) {
 	for msg := range messages {
 		if s.mainContext.Err() != nil {
 			return
 		}
+		metrics.MessagesReceived.Inc()
+
+		// matches published by the contest sync must not be written back to the forum
+		if msg.Metadata.Get(PropertySource) == SubscriberName {
+			metrics.MessagesDecoded.WithLabelValues(metrics.ResultSkipped).Inc()
+			log.Debug().Str("topic", s.topic).Msg("Skipping message published by this service")
+			msg.Ack()
+			continue
+		}
+
 		msgContent := messagebroker.Message{}
 
 		err := jsoniter.Unmarshal(msg.Payload, &msgContent)
 		if err != nil {
-			log.Info().Str("topic", s.topic).Interface("payload", string(msg.Payload)).Msg("Received message but error on unmarshal")
-			log.Error().Err(err).Msg("Error unmarshalling message")
+			metrics.MessagesDecoded.WithLabelValues(metrics.ResultError).Inc()
+			log.Error().Err(err).Str("topic", s.topic).Str("payload", string(msg.Payload)).Msg("Error unmarshalling message")
+			deadLetter(msg, fmt.Errorf("error unmarshalling message: %w", err))
 			continue
 		}
-		log.Info().Str("topic", s.topic).Interface("message", msgContent).Msgf("Received message: %+v", msgContent)
+		log.Info().Str("topic", s.topic).Str("subType", msgContent.SubType).Msg("Received message")
 
-		//switch msgContent.SubType {
-		//case messagebroker.UNWINDIA_MATCH_NEW.String(),
-		//	messagebroker.UNWINDIA_MATCH_NEW.String(),
-		//	messagebroker.UNWINDIA_MATCH_READY_A.String(),
-		//	messagebroker.UNWINDIA_MATCH_READY_B.String(),
-		//	messagebroker.UNWINDIA_MATCH_READY_ALL.String(),
-		//	messagebroker.UNWINDIA_MATCH_FINISHED.String():
+		subType, ok := messagebroker.EventsValue[msgContent.SubType]
+		if !ok {
+			metrics.MessagesDecoded.WithLabelValues(metrics.ResultSkipped).Inc()
+			log.Warn().Str("topic", s.topic).Str("subType", msgContent.SubType).Msg("Skipping message with unknown subtype")
+			msg.Ack()
+			continue
+		}
 
 		match := matchservice.MatchInfo{}
 		err = mapstructure.WeakDecode(msgContent.Data, &match)
 		if err != nil {
-			log.Error().Err(err).Msg("Error decoding match")
+			metrics.MessagesDecoded.WithLabelValues(metrics.ResultError).Inc()
+			log.Error().Err(err).Str("subType", msgContent.SubType).Msg("Error decoding match")
+			deadLetter(msg, fmt.Errorf("error decoding match: %w", err))
+			continue
 		}
+		metrics.MessagesDecoded.WithLabelValues(metrics.ResultSuccess).Inc()
 
-		log.Info().Str("topic", s.topic).Interface("match", match).Msg("Received match")
+		// the MatchInfo is not logged, as it holds the server passwords
+		log.Info().Str("topic", s.topic).Str("subType", msgContent.SubType).Str("matchId", match.MsID).Msg("Received match")
 
-		s.matchInfoChan <- &match
-		//}
+		s.matchEventChan <- &MatchEvent{
+			SubType:   subType,
+			MatchInfo: &match,
+			message:   msg,
+		}
 	}
 }
 
+// StartConsumer starts receiving messages from pulsar. A message is acknowledged after its MatchEvent was acknowledged
+// by the server, negatively acknowledged messages are redelivered by pulsar until they reach the maximum amount of
+// redeliveries.
 func (s *Subscriber) StartConsumer() {
 	messageChan := make(chan *message.Message)
 
@@ -103,22 +159,17 @@ func (s *Subscriber) StartConsumer() {
 			if err != nil {
 				log.Error().Err(err).Msg("Error receiving message")
 				continue
-			} else {
-				response := make(map[string]interface{})
-				err = jsoniter.Unmarshal(msg.Payload(), &response)
-				if err != nil {
-					s.pulsarConsumer.Nack(msg)
-				}
-				log.Info().Msgf("[%s] Received message : %v", s.topic, response)
-				messageChan <- &message.Message{
-					UUID:    msg.Key(),
-					Payload: msg.Payload(),
-				}
 			}
 
-			err = s.pulsarConsumer.Ack(msg)
-			if err != nil {
-				log.Error().Err(err).Msg("Error acking message")
+			log.Info().Str("topic", s.topic).Str("messageId", messageIdString(msg.ID())).Uint32("redeliveryCount", msg.RedeliveryCount()).Msg("Received message")
+
+			wmMsg := message.NewMessage(msg.Key(), msg.Payload())
+			wmMsg.Metadata.Set(PropertySource, msg.Properties()[PropertySource])
+			go s.awaitAck(msg, wmMsg)
+
+			select {
+			case messageChan <- wmMsg:
+			case <-s.mainContext.Done():
 			}
 		}
 	}()
@@ -127,3 +178,76 @@ func (s *Subscriber) StartConsumer() {
 
 	log.Info().Str("topic", s.topic).Msg("Started pulsar subscriber")
 }
+
+// ackAction is the way a received pulsar message is acknowledged after processing it
+type ackAction int
+
+const (
+	// actionAck acknowledges the message, so it is not delivered again
+	actionAck ackAction = iota
+	// actionRedeliver negatively acknowledges the message, so pulsar redelivers it after the nack redelivery delay
+	actionRedeliver
+	// actionDeadLetter moves the message to the dead letter topic
+	actionDeadLetter
+)
+
+// ackDecision decides how a message is acknowledged after its processing was acked or nacked. Failed messages are
+// redelivered until they were attempted more than maxRedeliveries times, messages marked as dead letter are never
+// redelivered.
+func ackDecision(acked, deadLetter bool, attempts, maxRedeliveries uint32) ackAction {
+	if acked {
+		return actionAck
+	}
+	if !deadLetter && attempts <= maxRedeliveries {
+		return actionRedeliver
+	}
+	return actionDeadLetter
+}
+
+// awaitAck waits for the processing result of the message and acknowledges the pulsar message accordingly
+func (s *Subscriber) awaitAck(msg pulsar.Message, wmMsg *message.Message) {
+	log := log.With().Str("topic", s.topic).Str("messageId", messageIdString(msg.ID())).Logger()
+
+	attempts := msg.RedeliveryCount() + 1
+	var action ackAction
+	select {
+	case <-wmMsg.Acked():
+		action = ackDecision(true, false, attempts, s.maxRedeliveries)
+	case <-wmMsg.Nacked():
+		action = ackDecision(false, wmMsg.Metadata.Get(MetadataDeadLetter) != "", attempts, s.maxRedeliveries)
+	case <-s.mainContext.Done():
+		// the message stays unacknowledged and is redelivered after reconnecting
+		return
+	}
+
+	reason := wmMsg.Metadata.Get(MetadataFailureReason)
+	switch action {
+	case actionRedeliver:
+		log.Warn().Uint32("attempts", attempts).Str("reason", reason).Msg("Processing message failed, it will be redelivered")
+		s.pulsarConsumer.Nack(msg)
+		return
+	case actionDeadLetter:
+		if err := s.deadLetterQueue.Publish(s.mainContext, msg, reason, attempts); err != nil {
+			log.Error().Err(err).Msg("Error moving message to dead letter topic, it will be redelivered")
+			s.pulsarConsumer.Nack(msg)
+			return
+		}
+	}
+
+	if err := s.pulsarConsumer.Ack(msg); err != nil {
+		log.Error().Err(err).Msg("Error acking message")
+	}
+}
+
+// deadLetter negatively acknowledges a message which can never be processed, so it is moved to the dead letter topic
+// immediately
+func deadLetter(msg *message.Message, reason error) {
+	msg.Metadata.Set(MetadataFailureReason, reason.Error())
+	msg.Metadata.Set(MetadataDeadLetter, "true")
+	msg.Nack()
+}
+
+// messageIdString returns a human-readable representation of the message id for logging
+func messageIdString(id pulsar.MessageID) string {
+	return fmt.Sprintf("%d:%d:%d:%d", id.LedgerID(), id.EntryID(), id.PartitionIdx(), id.BatchIdx())
+}
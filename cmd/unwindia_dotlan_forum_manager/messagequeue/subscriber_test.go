@@ -0,0 +1,116 @@
+package messagequeue
+
+import (
+	"context"
+	"github.com/GSH-LAN/Unwindia_common/src/go/messagebroker"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"testing"
+)
+
+func TestSubscriber_processMessages(t *testing.T) {
+	tests := []struct {
+		name           string
+		payload        string
+		source         string
+		wantSubType    messagebroker.MatchEvent
+		wantEvent      bool
+		wantAcked      bool
+		wantDeadLetter bool
+	}{
+		{
+			name:        "ok-known_subtype",
+			payload:     `{"type":0,"subtype":"UNWINDIA_MATCH_READY_ALL","data":{"MsID":"1001"}}`,
+			wantSubType: messagebroker.UNWINDIA_MATCH_READY_ALL,
+			wantEvent:   true,
+		},
+		{
+			name:      "ok-unknown_subtype_skipped",
+			payload:   `{"type":0,"subtype":"UNWINDIA_MATCH_SOMETHING","data":{"MsID":"1001"}}`,
+			wantAcked: true,
+		},
+		{
+			name:      "ok-own_message_skipped",
+			payload:   `{"type":0,"subtype":"UNWINDIA_MATCH_NEW","data":{"MsID":"1001"}}`,
+			source:    SubscriberName,
+			wantAcked: true,
+		},
+		{
+			name:           "err-broken_payload",
+			payload:        `{broken`,
+			wantDeadLetter: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matchEventChan := make(chan *MatchEvent, 1)
+			s := &Subscriber{
+				mainContext:    context.Background(),
+				topic:          "test",
+				matchEventChan: matchEventChan,
+			}
+
+			msg := message.NewMessage("1001", []byte(tt.payload))
+			msg.Metadata.Set(PropertySource, tt.source)
+			messages := make(chan *message.Message, 1)
+			messages <- msg
+			close(messages)
+
+			s.processMessages(messages)
+
+			select {
+			case event := <-matchEventChan:
+				if !tt.wantEvent {
+					t.Fatalf("processMessages() event = %v, want none", event)
+				}
+				if event.SubType != tt.wantSubType || event.MatchInfo.MsID != "1001" {
+					t.Errorf("processMessages() event = %v %v, want %v 1001", event.SubType, event.MatchInfo.MsID, tt.wantSubType)
+				}
+			default:
+				if tt.wantEvent {
+					t.Fatalf("processMessages() no event, want %v", tt.wantSubType)
+				}
+			}
+
+			select {
+			case <-msg.Acked():
+				if !tt.wantAcked {
+					t.Errorf("processMessages() acked message, want not acked")
+				}
+			default:
+				if tt.wantAcked {
+					t.Errorf("processMessages() did not ack message, want acked")
+				}
+			}
+
+			if gotDeadLetter := msg.Metadata.Get(MetadataDeadLetter) != ""; gotDeadLetter != tt.wantDeadLetter {
+				t.Errorf("processMessages() dead letter = %v, want %v", gotDeadLetter, tt.wantDeadLetter)
+			}
+		})
+	}
+}
+
+func TestAckDecision(t *testing.T) {
+	tests := []struct {
+		name            string
+		acked           bool
+		deadLetter      bool
+		attempts        uint32
+		maxRedeliveries uint32
+		want            ackAction
+	}{
+		{name: "ok-acked", acked: true, attempts: 1, maxRedeliveries: 3, want: actionAck},
+		{name: "ok-acked_after_redeliveries", acked: true, attempts: 4, maxRedeliveries: 3, want: actionAck},
+		{name: "ok-nacked_redelivered", attempts: 1, maxRedeliveries: 3, want: actionRedeliver},
+		{name: "ok-nacked_last_redelivery", attempts: 3, maxRedeliveries: 3, want: actionRedeliver},
+		{name: "ok-nacked_redeliveries_exceeded", attempts: 4, maxRedeliveries: 3, want: actionDeadLetter},
+		{name: "ok-nacked_without_redeliveries", attempts: 1, maxRedeliveries: 0, want: actionDeadLetter},
+		{name: "ok-nacked_dead_letter", deadLetter: true, attempts: 1, maxRedeliveries: 3, want: actionDeadLetter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ackDecision(tt.acked, tt.deadLetter, tt.attempts, tt.maxRedeliveries); got != tt.want {
+				t.Errorf("ackDecision() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"github.com/gammazero/workerpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	namespace = "unwindia_dotlan_forum_manager"
+
+	ResultSuccess = "success"
+	ResultError   = "error"
+	ResultSkipped = "skipped"
+
+	OperationThreadCreated  = "thread_created"
+	OperationPostCreated    = "post_created"
+	OperationPostUpdated    = "post_updated"
+	OperationThreadClosed   = "thread_closed"
+	OperationPostLocked     = "post_locked"
+	OperationTitleUpdated   = "title_updated"
+	OperationThreadArchived = "thread_archived"
+	OperationPMSent         = "pm_sent"
+
+	DriftThreadMissing = "thread_missing"
+	DriftPostMissing   = "post_missing"
+
+	CommandPublished = "published"
+	CommandRejected  = "rejected"
+)
+
+var (
+	// MessagesReceived counts all messages received from the message queue
+	MessagesReceived = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_received_total",
+		Help:      "Total number of messages received from the message queue",
+	})
+
+	// MessagesDecoded counts the decoded messages by result
+	MessagesDecoded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_decoded_total",
+		Help:      "Total number of decoded messages by result",
+	}, []string{"result"})
+
+	// MessagesDeadLettered counts the messages moved to the dead letter topic
+	MessagesDeadLettered = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_dead_lettered_total",
+		Help:      "Total number of messages moved to the dead letter topic",
+	})
+
+	// MatchEventsCoalesced counts the match events which were superseded by a newer event of the same match
+	MatchEventsCoalesced = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "match_events_coalesced_total",
+		Help:      "Total number of match events superseded by a newer event of the same match within the debounce window",
+	})
+
+	// MatchCommentsPublished counts the replies to match threads published on the message queue
+	MatchCommentsPublished = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "match_comments_published_total",
+		Help:      "Total number of replies to match threads published on the message queue",
+	})
+
+	// MatchCommands counts the chat commands of match threads by command and whether they were published or rejected
+	MatchCommands = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "match_commands_total",
+		Help:      "Total number of chat commands in match threads by command and status",
+	}, []string{"command", "status"})
+
+	// MatchesPublished counts the dotlan contests published as matches on the message queue by subtype
+	MatchesPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "matches_published_total",
+		Help:      "Total number of dotlan contests published as matches on the message queue by subtype",
+	}, []string{"subtype"})
+
+	// TemplateRenderFailures counts failed renderings of forum templates
+	TemplateRenderFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "template_render_failures_total",
+		Help:      "Total number of failed template renderings",
+	})
+
+	// ForumWrites counts the writes to the dotlan forum by operation
+	ForumWrites = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "forum_writes_total",
+		Help:      "Total number of writes to the dotlan forum by operation",
+	}, []string{"operation"})
+
+	// ForumWritesSkipped counts the updates of forum posts which were skipped, since the rendered text was unchanged
+	ForumWritesSkipped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "forum_writes_skipped_total",
+		Help:      "Total number of skipped forum post updates because the rendered text was unchanged",
+	})
+
+	// ForumOperationsSkipped counts the forum operations by operation, which were skipped since they were already done
+	// before, e.g. closing an already closed thread
+	ForumOperationsSkipped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "forum_operations_skipped_total",
+		Help:      "Total number of skipped forum operations by operation because they were already done",
+	}, []string{"operation"})
+
+	// ForumDrift counts the detected differences between the stored forum states and the dotlan forum by kind
+	ForumDrift = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "forum_drift_total",
+		Help:      "Total number of detected differences between the stored forum states and the dotlan forum by kind",
+	}, []string{"kind"})
+
+	// MatchProcessingDuration observes the duration of processing a single match event by result
+	MatchProcessingDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "match_processing_duration_seconds",
+		Help:      "Duration of processing a single match event from rendering to writing the forum post",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"result"})
+
+	// MySQLQueryDuration observes the duration of operations against the dotlan MySQL database
+	MySQLQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mysql_query_duration_seconds",
+		Help:      "Duration of operations against the dotlan MySQL database",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	// MongoDBQueryDuration observes the duration of operations against MongoDB
+	MongoDBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mongodb_query_duration_seconds",
+		Help:      "Duration of operations against MongoDB",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+)
+
+// RegisterWorkerpool registers gauges reporting the queue depth of the given workerpool and the backlog of the match
+// executor, as returned by executorBacklog, which holds the tasks of a match back while an earlier task of the match runs
+func RegisterWorkerpool(wp *workerpool.WorkerPool, executorBacklog func() int) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "workerpool_waiting_queue_size",
+		Help:      "Number of tasks waiting in the workerpool queue",
+	}, func() float64 {
+		return float64(wp.WaitingQueueSize())
+	})
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "executor_backlog_size",
+		Help:      "Number of match tasks waiting for an earlier task of the same match",
+	}, func() float64 {
+		return float64(executorBacklog())
+	})
+}
@@ -0,0 +1,52 @@
+package router
+
+import (
+	"context"
+	"errors"
+	jsoniter "github.com/json-iterator/go"
+	"io"
+	"net/http"
+)
+
+// MatchArchiver moves the forum threads of matches to the archive forum
+type MatchArchiver interface {
+	// ArchiveMatches archives the threads of the matches with the given ids, or of all finished matches if no ids are
+	// given. With includeUnfinished the threads of all matches are archived. It returns the ids of the archived and of
+	// the failed matches.
+	ArchiveMatches(ctx context.Context, ids []string, includeUnfinished bool) (archived []string, failed []string, err error)
+}
+
+type archiveRequest struct {
+	IDs               []string `json:"ids"`
+	IncludeUnfinished bool     `json:"includeUnfinished"`
+}
+
+type archiveResponse struct {
+	Archived []string `json:"archived"`
+	Failed   []string `json:"failed"`
+}
+
+// archiveMatches handles POST /api/v1/matches/archive with an optional body {"ids": ["..."], "includeUnfinished": false}
+func (r *Router) archiveMatches(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var request archiveRequest
+	if err := jsoniter.NewDecoder(req.Body).Decode(&request); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), requestTimeout)
+	defer cancel()
+
+	archived, failed, err := r.archiver.ArchiveMatches(ctx, request.IDs, request.IncludeUnfinished)
+	if err != nil {
+		writeError(w, statusCodeForError(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, archiveResponse{Archived: archived, Failed: failed})
+}
@@ -0,0 +1,100 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"github.com/GSH-LAN/Unwindia_common/src/go/matchservice"
+	"github.com/GSH-LAN/Unwindia_common/src/go/messagebroker"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/messagequeue"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/mitchellh/mapstructure"
+	"io"
+	"net/http"
+)
+
+// DeadLetterAdmin gives access to the messages which could not be processed
+type DeadLetterAdmin interface {
+	// List returns all messages of the dead letter topic which were not replayed yet
+	List(ctx context.Context) ([]messagequeue.DeadLetter, error)
+	// Replay publishes the dead letters with the given ids, or all if no ids are given, onto the main topic again.
+	// Replayed letters are not listed or replayed again.
+	Replay(ctx context.Context, ids []string) (int, error)
+}
+
+type replayRequest struct {
+	IDs []string `json:"ids"`
+}
+
+type replayResponse struct {
+	Replayed int `json:"replayed"`
+}
+
+// listDeadLetters handles GET /api/v1/deadletters
+func (r *Router) listDeadLetters(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), requestTimeout)
+	defer cancel()
+
+	deadLetters, err := r.deadLetters.List(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	for i := range deadLetters {
+		deadLetters[i].Payload = r.redactPayload(deadLetters[i].Payload)
+	}
+
+	writeJSON(w, http.StatusOK, deadLetters)
+}
+
+// redactPayload masks the sensitive MatchInfo fields within the payload of a dead letter. Payloads which can not be
+// decoded as match message can not be redacted and are dropped.
+func (r *Router) redactPayload(payload string) string {
+	var msg messagebroker.Message
+	if err := jsoniter.UnmarshalFromString(payload, &msg); err != nil {
+		return ""
+	}
+
+	var matchInfo matchservice.MatchInfo
+	if err := mapstructure.WeakDecode(msg.Data, &matchInfo); err != nil {
+		return ""
+	}
+	msg.Data = r.redaction.Redact(&matchInfo)
+
+	redacted, err := jsoniter.MarshalToString(msg)
+	if err != nil {
+		return ""
+	}
+
+	return redacted
+}
+
+// replayDeadLetters handles POST /api/v1/deadletters/replay with an optional body {"ids": ["..."]}
+func (r *Router) replayDeadLetters(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var request replayRequest
+	if err := jsoniter.NewDecoder(req.Body).Decode(&request); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), requestTimeout)
+	defer cancel()
+
+	replayed, err := r.deadLetters.Replay(ctx, request.IDs)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, replayResponse{Replayed: replayed})
+}
@@ -0,0 +1,86 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	healthCheckTimeout = 5 * time.Second
+	statusOk           = "ok"
+	statusError        = "error"
+)
+
+// HealthCheck checks the availability of a single dependency and returns an error if it is not usable
+type HealthCheck func(ctx context.Context) error
+
+type healthResponse struct {
+	Status string                         `json:"status"`
+	Checks map[string]healthCheckResponse `json:"checks,omitempty"`
+}
+
+type healthCheckResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// liveness handles GET /healthz, which only reports that the process is able to serve requests
+func (r *Router) liveness(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, healthResponse{Status: statusOk})
+}
+
+// readiness handles GET /readyz and reports the status of every registered dependency
+func (r *Router) readiness(w http.ResponseWriter, req *http.Request) {
+	response := healthResponse{
+		Status: statusOk,
+		Checks: make(map[string]healthCheckResponse, len(r.readinessChecks)),
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range r.readinessChecks {
+		wg.Add(1)
+		go func(name string, check HealthCheck) {
+			defer wg.Done()
+			result := healthCheckResponse{Status: statusOk}
+			if err := runHealthCheck(req.Context(), check); err != nil {
+				result = healthCheckResponse{Status: statusError, Error: err.Error()}
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			response.Checks[name] = result
+			if result.Status != statusOk {
+				response.Status = statusError
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	if response.Status != statusOk {
+		status = http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, status, response)
+}
+
+// runHealthCheck executes the check with a timeout, so a hanging dependency is reported instead of blocking the probe
+func runHealthCheck(ctx context.Context, check HealthCheck) error {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- check(ctx)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -1 +1,259 @@
 package router
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/database"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/dotlan"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/template"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	apiBasePath     = "/api/v1"
+	matchesPath     = apiBasePath + "/matches"
+	errorsPath      = apiBasePath + "/errors"
+	deadLettersPath = apiBasePath + "/deadletters"
+	livenessPath    = "/healthz"
+	readinessPath   = "/readyz"
+	metricsPath     = "/metrics"
+	requestTimeout  = 30 * time.Second
+)
+
+// MatchRenderer renders the forum post of an already known match again
+type MatchRenderer interface {
+	// RenderMatch renders and writes the forum post for the match with the given id using its last known MatchInfo
+	RenderMatch(ctx context.Context, id string) error
+}
+
+// Router serves either the probes and metrics or the HTTP admin api of the forum manager
+type Router struct {
+	mux             *http.ServeMux
+	dbClient        database.DatabaseClient
+	renderer        MatchRenderer
+	archiver        MatchArchiver
+	deadLetters     DeadLetterAdmin
+	redaction       *template.RedactionPolicy
+	token           string
+	readinessChecks map[string]HealthCheck
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type matchErrorResponse struct {
+	ID          string    `json:"unwindiaMatchID"`
+	LastError   string    `json:"lastError"`
+	LastErrorAt time.Time `json:"lastErrorAt"`
+}
+
+// NewRouter creates the Router for the probes and metrics. The readinessChecks are executed on every readiness probe,
+// keyed by the name of the dependency they check.
+func NewRouter(readinessChecks map[string]HealthCheck) *Router {
+	r := Router{
+		mux:             http.NewServeMux(),
+		readinessChecks: readinessChecks,
+	}
+
+	r.mux.HandleFunc(livenessPath, r.liveness)
+	r.mux.HandleFunc(readinessPath, r.readiness)
+	r.mux.Handle(metricsPath, promhttp.Handler())
+
+	return &r
+}
+
+// NewAdminRouter creates the Router for the admin api. Every request has to carry the token as bearer token, all
+// requests are rejected if the token is empty. The sensitive MatchInfo fields of the redaction policy are masked in
+// all responses.
+func NewAdminRouter(dbClient database.DatabaseClient, renderer MatchRenderer, archiver MatchArchiver, deadLetters DeadLetterAdmin, redaction *template.RedactionPolicy, token string) *Router {
+	r := Router{
+		mux:         http.NewServeMux(),
+		dbClient:    dbClient,
+		renderer:    renderer,
+		archiver:    archiver,
+		deadLetters: deadLetters,
+		redaction:   redaction,
+		token:       token,
+	}
+
+	r.mux.HandleFunc(matchesPath, r.authorized(r.listMatches))
+	r.mux.HandleFunc(matchesPath+"/", r.authorized(r.match))
+	r.mux.HandleFunc(matchesPath+"/archive", r.authorized(r.archiveMatches))
+	r.mux.HandleFunc(errorsPath, r.authorized(r.listErrors))
+	r.mux.HandleFunc(deadLettersPath, r.authorized(r.listDeadLetters))
+	r.mux.HandleFunc(deadLettersPath+"/replay", r.authorized(r.replayDeadLetters))
+
+	return &r
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	log.Debug().Str("method", req.Method).Str("path", req.URL.Path).Msg("Received http request")
+	r.mux.ServeHTTP(w, req)
+}
+
+// authorized rejects requests which do not carry the token of the admin api as bearer token
+func (r *Router) authorized(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if r.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(r.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+			return
+		}
+		handler(w, req)
+	}
+}
+
+// listMatches handles GET /api/v1/matches
+func (r *Router) listMatches(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	entries, err := r.list(req.Context(), nil)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	for i := range entries {
+		entries[i].MatchInfo = r.redaction.Redact(entries[i].MatchInfo)
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// listErrors handles GET /api/v1/errors
+func (r *Router) listErrors(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	entries, err := r.list(req.Context(), database.FilterWithError())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	matchErrors := make([]matchErrorResponse, 0, len(entries))
+	for _, entry := range entries {
+		matchErrors = append(matchErrors, toMatchErrorResponse(&entry))
+	}
+
+	writeJSON(w, http.StatusOK, matchErrors)
+}
+
+// match handles all requests below /api/v1/matches/{id}:
+//
+//	GET  /api/v1/matches/{id}
+//	GET  /api/v1/matches/{id}/error
+//	POST /api/v1/matches/{id}/render
+func (r *Router) match(w http.ResponseWriter, req *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(req.URL.Path, matchesPath), "/"), "/")
+	id := parts[0]
+	if id == "" || len(parts) > 2 {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	action := ""
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), requestTimeout)
+	defer cancel()
+
+	switch {
+	case action == "" && req.Method == http.MethodGet:
+		entry, err := r.dbClient.Get(ctx, id)
+		if err != nil {
+			writeError(w, statusCodeForError(err), err)
+			return
+		}
+		r.writeEntry(w, entry)
+	case action == "error" && req.Method == http.MethodGet:
+		entry, err := r.dbClient.Get(ctx, id)
+		if err != nil {
+			writeError(w, statusCodeForError(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toMatchErrorResponse(entry))
+	case action == "render" && req.Method == http.MethodPost:
+		if err := r.renderer.RenderMatch(ctx, id); err != nil {
+			writeError(w, statusCodeForError(err), err)
+			return
+		}
+		entry, err := r.dbClient.Get(ctx, id)
+		if err != nil {
+			writeError(w, statusCodeForError(err), err)
+			return
+		}
+		r.writeEntry(w, entry)
+	case action == "" || action == "error" || action == "render":
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+// writeEntry writes the forum status of a match with its sensitive MatchInfo fields masked
+func (r *Router) writeEntry(w http.ResponseWriter, entry *database.DotlanForumStatus) {
+	entry.MatchInfo = r.redaction.Redact(entry.MatchInfo)
+	writeJSON(w, http.StatusOK, entry)
+}
+
+func (r *Router) list(ctx context.Context, filter interface{}) (database.DotlanForumStatusList, error) {
+	resultChan := make(chan database.Result, 1)
+	r.dbClient.List(ctx, filter, resultChan)
+	result := <-resultChan
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if result.Result == nil {
+		return database.DotlanForumStatusList{}, nil
+	}
+
+	return result.Result, nil
+}
+
+func toMatchErrorResponse(entry *database.DotlanForumStatus) matchErrorResponse {
+	return matchErrorResponse{
+		ID:          entry.ID,
+		LastError:   entry.LastError,
+		LastErrorAt: entry.LastErrorAt,
+	}
+}
+
+func statusCodeForError(err error) int {
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return http.StatusNotFound
+	case errors.Is(err, database.ErrNoMatchInfo), errors.Is(err, database.ErrUnknownLastEvent), errors.Is(err, dotlan.ErrNoArchiveForum):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := jsoniter.NewEncoder(w).Encode(v); err != nil {
+		log.Error().Err(err).Msg("Error writing http response")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,384 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"github.com/GSH-LAN/Unwindia_common/src/go/matchservice"
+	"github.com/GSH-LAN/Unwindia_common/src/go/messagebroker"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/database"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/dotlan"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/messagequeue"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/template"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testDatabaseClient struct {
+	entries map[string]database.DotlanForumStatus
+}
+
+func (t *testDatabaseClient) Upsert(_ context.Context, entry *database.DotlanForumStatus) error {
+	t.entries[entry.ID] = *entry
+	return nil
+}
+
+func (t *testDatabaseClient) Get(_ context.Context, id string) (*database.DotlanForumStatus, error) {
+	entry, ok := t.entries[id]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &entry, nil
+}
+
+func (t *testDatabaseClient) Ping(_ context.Context) error {
+	return nil
+}
+
+func (t *testDatabaseClient) List(_ context.Context, _ interface{}, resultChan chan database.Result) {
+	var result database.DotlanForumStatusList
+	for _, entry := range t.entries {
+		result = append(result, entry)
+	}
+	resultChan <- database.Result{Result: result}
+}
+
+func (t *testDatabaseClient) ListContestHashes(_ context.Context) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+func (t *testDatabaseClient) UpsertContestHash(_ context.Context, _, _ string) error {
+	return nil
+}
+
+type testRenderer struct {
+	dbClient database.DatabaseClient
+}
+
+func (t *testRenderer) RenderMatch(ctx context.Context, id string) error {
+	entry, err := t.dbClient.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if entry.MatchInfo == nil {
+		return database.ErrNoMatchInfo
+	}
+	if _, ok := messagebroker.EventsValue[entry.LastEvent]; !ok {
+		return database.ErrUnknownLastEvent
+	}
+	return nil
+}
+
+type testArchiver struct{}
+
+func (t *testArchiver) ArchiveMatches(_ context.Context, ids []string, _ bool) ([]string, []string, error) {
+	if len(ids) == 0 {
+		return nil, nil, dotlan.ErrNoArchiveForum
+	}
+	return ids, []string{}, nil
+}
+
+const (
+	testAdminToken = "test-token"
+	testSecret     = "s3cr3t"
+)
+
+type testDeadLetterAdmin struct{}
+
+func (t *testDeadLetterAdmin) List(_ context.Context) ([]messagequeue.DeadLetter, error) {
+	return []messagequeue.DeadLetter{
+		{ID: "1", FailureReason: "broken", Payload: `{"type":0,"subtype":"UNWINDIA_MATCH_NEW","data":{"msID":"1","serverPassword":"` + testSecret + `"}}`},
+		{ID: "2", FailureReason: "undecodable", Payload: `{broken ` + testSecret},
+	}, nil
+}
+
+func (t *testDeadLetterAdmin) Replay(_ context.Context, ids []string) (int, error) {
+	if len(ids) == 0 {
+		return 1, nil
+	}
+	return len(ids), nil
+}
+
+func TestRouter_ServeHTTP(t *testing.T) {
+	dbClient := &testDatabaseClient{entries: map[string]database.DotlanForumStatus{
+		"1": {ID: "1", DotlanForumThreadID: 10, DotlanForumPostID: 20},
+		"2": {ID: "2", LastError: "something failed"},
+		"3": {ID: "3", LastEvent: "UNWINDIA_MATCH_NEW", MatchInfo: &matchservice.MatchInfo{MsID: "3", ServerPassword: testSecret, ServerPasswordMgmt: testSecret}},
+		"5": {ID: "5", MatchInfo: &matchservice.MatchInfo{MsID: "5"}},
+	}}
+	redaction, err := template.NewRedactionPolicy(template.CredentialFields, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := NewAdminRouter(dbClient, &testRenderer{dbClient: dbClient}, &testArchiver{}, &testDeadLetterAdmin{}, redaction, testAdminToken)
+	probes := NewRouter(map[string]HealthCheck{
+		"ok": func(ctx context.Context) error {
+			return nil
+		},
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		token      string
+		wantStatus int
+	}{
+		{
+			name:       "list_matches",
+			method:     http.MethodGet,
+			path:       "/api/v1/matches",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "get_match",
+			method:     http.MethodGet,
+			path:       "/api/v1/matches/1",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "get_unknown_match",
+			method:     http.MethodGet,
+			path:       "/api/v1/matches/4",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "get_match_error",
+			method:     http.MethodGet,
+			path:       "/api/v1/matches/2/error",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "render_match_without_matchinfo",
+			method:     http.MethodPost,
+			path:       "/api/v1/matches/1/render",
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "render_match",
+			method:     http.MethodPost,
+			path:       "/api/v1/matches/3/render",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "render_match_without_last_event",
+			method:     http.MethodPost,
+			path:       "/api/v1/matches/5/render",
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "render_match_wrong_method",
+			method:     http.MethodGet,
+			path:       "/api/v1/matches/1/render",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "unknown_action",
+			method:     http.MethodGet,
+			path:       "/api/v1/matches/1/unknown",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "archive_matches",
+			method:     http.MethodPost,
+			path:       "/api/v1/matches/archive",
+			body:       `{"ids": ["1"]}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "archive_matches_without_archive_forum",
+			method:     http.MethodPost,
+			path:       "/api/v1/matches/archive",
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "archive_matches_broken_body",
+			method:     http.MethodPost,
+			path:       "/api/v1/matches/archive",
+			body:       `{broken`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "archive_matches_wrong_method",
+			method:     http.MethodGet,
+			path:       "/api/v1/matches/archive",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "list_deadletters",
+			method:     http.MethodGet,
+			path:       "/api/v1/deadletters",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "replay_deadletters",
+			method:     http.MethodPost,
+			path:       "/api/v1/deadletters/replay",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "list_errors",
+			method:     http.MethodGet,
+			path:       "/api/v1/errors",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "get_match_with_credentials",
+			method:     http.MethodGet,
+			path:       "/api/v1/matches/3",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing_token",
+			method:     http.MethodGet,
+			path:       "/api/v1/matches",
+			token:      "-",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong_token",
+			method:     http.MethodPost,
+			path:       "/api/v1/deadletters/replay",
+			token:      "wrong",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "metrics_not_on_admin_api",
+			method:     http.MethodGet,
+			path:       "/metrics",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			switch tt.token {
+			case "":
+				req.Header.Set("Authorization", "Bearer "+testAdminToken)
+			case "-":
+			default:
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			rec := httptest.NewRecorder()
+			admin.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ServeHTTP() status = %v, want %v, body: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if strings.Contains(rec.Body.String(), testSecret) {
+				t.Errorf("ServeHTTP() body contains credentials: %s", rec.Body.String())
+			}
+		})
+	}
+
+	probeTests := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{
+			name:       "liveness",
+			path:       "/healthz",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "readiness",
+			path:       "/readyz",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "admin_api_not_on_probes",
+			path:       "/api/v1/matches",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range probeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			probes.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ServeHTTP() status = %v, want %v, body: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRouter_readiness(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     map[string]HealthCheck
+		wantStatus int
+	}{
+		{
+			name: "all_ok",
+			checks: map[string]HealthCheck{
+				"mysql":   func(ctx context.Context) error { return nil },
+				"mongodb": func(ctx context.Context) error { return nil },
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "one_failing",
+			checks: map[string]HealthCheck{
+				"mysql":   func(ctx context.Context) error { return errors.New("connection refused") },
+				"mongodb": func(ctx context.Context) error { return nil },
+			},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name: "hanging",
+			checks: map[string]HealthCheck{
+				"pulsar": func(ctx context.Context) error {
+					<-ctx.Done()
+					return ctx.Err()
+				},
+			},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRouter(tt.checks)
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("readiness() status = %v, want %v, body: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRouter_redactPayload(t *testing.T) {
+	redaction, err := template.NewRedactionPolicy(template.CredentialFields, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewAdminRouter(&testDatabaseClient{}, nil, nil, nil, redaction, testAdminToken)
+
+	tests := []struct {
+		name        string
+		payload     string
+		wantContain string
+	}{
+		{
+			name:        "ok-match_message",
+			payload:     `{"type":0,"subtype":"UNWINDIA_MATCH_NEW","data":{"msID":"1","serverPassword":"` + testSecret + `"}}`,
+			wantContain: template.RedactedValue,
+		},
+		{
+			name:    "ok-undecodable_dropped",
+			payload: `{broken ` + testSecret,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := r.redactPayload(tt.payload)
+			if strings.Contains(got, testSecret) || !strings.Contains(got, tt.wantContain) {
+				t.Errorf("redactPayload() got = %v, want without credentials and containing %q", got, tt.wantContain)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package routing
+
+import "strings"
+
+// Route maps the matches of a game and/or tournament to a dotlan forum. Empty fields match every value.
+type Route struct {
+	Game       string `json:"game,omitempty"`
+	Tournament string `json:"tournament,omitempty"`
+	ForumID    int    `json:"forumId"`
+}
+
+// Table is the routing table of forums for match threads
+type Table []Route
+
+// ForumID returns the forum id of the most specific route matching the game and tournament. Routes for game and
+// tournament win over routes for a tournament, which win over routes for a game. Routes of the same specificity are
+// matched in order. Games and tournaments are compared case-insensitively.
+func (t Table) ForumID(game, tournament string) (int, bool) {
+	forumId := 0
+	bestScore := 0
+
+	for _, route := range t {
+		if route.ForumID <= 0 {
+			continue
+		}
+
+		score := 1
+		if route.Game != "" {
+			if !strings.EqualFold(route.Game, game) {
+				continue
+			}
+			score += 1
+		}
+		if route.Tournament != "" {
+			if !strings.EqualFold(route.Tournament, tournament) {
+				continue
+			}
+			score += 2
+		}
+
+		if score > bestScore {
+			forumId = route.ForumID
+			bestScore = score
+		}
+	}
+
+	return forumId, bestScore > 0
+}
@@ -0,0 +1,81 @@
+package routing
+
+import "testing"
+
+var testTable = Table{
+	{Game: "csgo", ForumID: 10},
+	{Game: "lol", ForumID: 20},
+	{Tournament: "Fun Cup", ForumID: 30},
+	{Game: "csgo", Tournament: "CS:GO 5on5", ForumID: 40},
+	{Game: "dota2"},
+}
+
+func TestTable_ForumID(t *testing.T) {
+	tests := []struct {
+		name       string
+		table      Table
+		game       string
+		tournament string
+		want       int
+		wantOk     bool
+	}{
+		{
+			name:   "ok-game",
+			table:  testTable,
+			game:   "lol",
+			want:   20,
+			wantOk: true,
+		},
+		{
+			name:       "ok-game_case_insensitive",
+			table:      testTable,
+			game:       "CSGO",
+			tournament: "CS:GO 2on2",
+			want:       10,
+			wantOk:     true,
+		},
+		{
+			name:       "ok-tournament_wins_over_game",
+			table:      testTable,
+			game:       "csgo",
+			tournament: "Fun Cup",
+			want:       30,
+			wantOk:     true,
+		},
+		{
+			name:       "ok-game_and_tournament",
+			table:      testTable,
+			game:       "csgo",
+			tournament: "CS:GO 5on5",
+			want:       40,
+			wantOk:     true,
+		},
+		{
+			name:   "ok-catch_all",
+			table:  Table{{Game: "csgo", ForumID: 10}, {ForumID: 99}},
+			game:   "lol",
+			want:   99,
+			wantOk: true,
+		},
+		{
+			name:   "nok-route_without_forum",
+			table:  testTable,
+			game:   "dota2",
+			wantOk: false,
+		},
+		{
+			name:   "nok-empty_table",
+			table:  Table{},
+			game:   "csgo",
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotOk := tt.table.ForumID(tt.game, tt.tournament)
+			if got != tt.want || gotOk != tt.wantOk {
+				t.Errorf("ForumID() = %v, %v, want %v, %v", got, gotOk, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"github.com/GSH-LAN/Unwindia_common/src/go/messagebroker"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/database"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/dotlan"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/messagequeue"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/metrics"
+	"github.com/rs/zerolog/log"
+	"sync"
+	"time"
+)
+
+// archiveForumThread moves the forum thread of the finished match to the archive forum. It is skipped if no archive
+// forum is configured.
+func (s *Server) archiveForumThread(event *messagequeue.MatchEvent) error {
+	if s.env.DotlanArchiveForumId <= 0 {
+		return nil
+	}
+
+	dotlanForumState, err := s.dbClient.Get(context.TODO(), event.MatchInfo.MsID)
+	if err != nil {
+		return fmt.Errorf("failed to get dotlan forum state: %w", err)
+	}
+
+	return s.archiveThread(dotlanForumState)
+}
+
+// archiveThread moves the forum thread of the state to the archive forum and stores the state. Threads which are
+// already archived are skipped.
+func (s *Server) archiveThread(dotlanForumState *database.DotlanForumStatus) error {
+	if s.env.DotlanArchiveForumId <= 0 {
+		return dotlan.ErrNoArchiveForum
+	}
+
+	if dotlanForumState.DotlanForumThreadID <= 0 {
+		return fmt.Errorf("no forum thread to archive for match")
+	}
+
+	if dotlanForumState.ThreadArchived {
+		log.Debug().Str("matchId", dotlanForumState.ID).Msg("Forum thread is already archived, skipping")
+		metrics.ForumOperationsSkipped.WithLabelValues(metrics.OperationThreadArchived).Inc()
+		return nil
+	}
+
+	dotlanContext, cancel := context.WithTimeout(context.TODO(), time.Second*30)
+	defer cancel()
+
+	err := s.dotlanClient.ArchiveForumThread(dotlanContext, dotlanForumState.DotlanForumThreadID, s.env.DotlanArchiveForumId)
+	if err != nil {
+		return fmt.Errorf("error archiving forum thread for match: %w", err)
+	}
+
+	dotlanForumState.ThreadArchived = true
+	dotlanForumState.DotlanForumID = s.env.DotlanArchiveForumId
+	dotlanForumState.UpdatedAt = time.Now()
+
+	err = s.dbClient.Upsert(context.TODO(), dotlanForumState)
+	if err != nil {
+		return fmt.Errorf("error upserting dotlanForumState: %w", err)
+	}
+
+	return nil
+}
+
+// ArchiveMatches moves the forum threads of the matches with the given ids to the archive forum. If no ids are given,
+// the threads of all finished matches are archived, and with includeUnfinished the threads of all matches. It returns
+// the ids of the archived and of the failed matches, the error of a failed match is stored as its last error.
+func (s *Server) ArchiveMatches(ctx context.Context, ids []string, includeUnfinished bool) (archived []string, failed []string, err error) {
+	if s.env.DotlanArchiveForumId <= 0 {
+		return nil, nil, dotlan.ErrNoArchiveForum
+	}
+
+	selected := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		selected[id] = true
+	}
+
+	resultChan := make(chan database.Result, 1)
+	s.dbClient.List(ctx, nil, resultChan)
+	result := <-resultChan
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	archived, failed = []string{}, []string{}
+
+	for _, entry := range result.Result {
+		if entry.DotlanForumThreadID <= 0 || entry.ThreadArchived {
+			continue
+		}
+		if len(selected) > 0 && !selected[entry.ID] {
+			continue
+		}
+		if len(selected) == 0 && !includeUnfinished && !isFinished(&entry) {
+			continue
+		}
+
+		id := entry.ID
+		wg.Add(1)
+		s.executor.Submit(id, func() {
+			defer wg.Done()
+
+			err := s.archiveMatch(id)
+
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				failed = append(failed, id)
+			} else {
+				archived = append(archived, id)
+			}
+		})
+	}
+	wg.Wait()
+
+	log.Info().Int("archived", len(archived)).Int("failed", len(failed)).Msg("Archived forum threads of matches")
+
+	return archived, failed, nil
+}
+
+// archiveMatch archives the forum thread of a single match and records a failure as last error of the match
+func (s *Server) archiveMatch(id string) error {
+	dotlanForumState, err := s.dbClient.Get(context.TODO(), id)
+	if err != nil {
+		log.Error().Err(err).Str("matchId", id).Msg("Error getting forum state for archiving")
+		return err
+	}
+
+	if err = s.archiveThread(dotlanForumState); err != nil {
+		log.Error().Err(err).Str("matchId", id).Msg("Error archiving forum thread")
+		if dotlanForumState.MatchInfo != nil {
+			s.recordError(dotlanForumState.MatchInfo, err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// isFinished returns true if the match of the state has finished
+func isFinished(dotlanForumState *database.DotlanForumStatus) bool {
+	return dotlanForumState.ThreadClosed ||
+		dotlanForumState.LastEvent == messagebroker.UNWINDIA_MATCH_FINISHED.String() ||
+		(dotlanForumState.MatchInfo != nil && dotlanForumState.MatchInfo.Finished)
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/GSH-LAN/Unwindia_common/src/go/matchservice"
+	"github.com/GSH-LAN/Unwindia_common/src/go/messagebroker"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/dotlan"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/mongo"
+	"strconv"
+	"time"
+)
+
+// startContestSync periodically publishes new or changed dotlan contests as matches on the message queue, so results
+// entered within dotlan reach the other Unwindia services
+func (s *Server) startContestSync() {
+	if s.env.ContestSyncInterval <= 0 {
+		log.Info().Msg("Syncing dotlan contests is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.env.ContestSyncInterval)
+		defer ticker.Stop()
+
+		firstPass := true
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				if err := s.syncContests(firstPass); err != nil {
+					log.Error().Err(err).Msg("Error syncing dotlan contests")
+					continue
+				}
+				firstPass = false
+			}
+		}
+	}()
+}
+
+// syncContests publishes every contest whose hash differs from the hash stored when it was published last. The hash
+// excludes the comments counter, so the forum posts written for a published match do not trigger another sync. If no
+// hashes are stored yet on the first pass, the hashes of all existing contests are stored without publishing them, so
+// enabling the sync does not publish the whole history of dotlan.
+func (s *Server) syncContests(firstPass bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.env.ContestSyncInterval)
+	defer cancel()
+
+	hashes, err := s.dbClient.ListContestHashes(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing contest hashes: %w", err)
+	}
+
+	contests, err := s.dotlanClient.ListContests(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing dotlan contests: %w", err)
+	}
+
+	if firstPass && len(hashes) == 0 {
+		return s.seedContestHashes(ctx, contests)
+	}
+
+	published := 0
+	for i := range contests {
+		id := strconv.FormatUint(uint64(contests[i].Tcid), 10)
+		hash := contests[i].SyncHash()
+
+		lastHash, known := hashes[id]
+		if lastHash == hash {
+			continue
+		}
+
+		if err = s.publishContest(ctx, id, known); err != nil {
+			log.Error().Err(err).Str("matchId", id).Msg("Error publishing dotlan contest")
+			continue
+		}
+
+		if err = s.dbClient.UpsertContestHash(ctx, id, hash); err != nil {
+			log.Error().Err(err).Str("matchId", id).Msg("Error storing hash of published dotlan contest")
+			continue
+		}
+		published++
+	}
+
+	if published > 0 {
+		log.Info().Int("contests", published).Msg("Published changed dotlan contests")
+	}
+
+	return nil
+}
+
+// seedContestHashes stores the hashes of the contests without publishing them
+func (s *Server) seedContestHashes(ctx context.Context, contests []dotlan.Contest) error {
+	for i := range contests {
+		id := strconv.FormatUint(uint64(contests[i].Tcid), 10)
+		if err := s.dbClient.UpsertContestHash(ctx, id, contests[i].SyncHash()); err != nil {
+			return fmt.Errorf("error storing hash of dotlan contest %s: %w", id, err)
+		}
+	}
+
+	log.Info().Int("contests", len(contests)).Msg("Stored hashes of existing dotlan contests without publishing them")
+	return nil
+}
+
+// publishContest publishes the contest with its teams as MatchInfo. Contests which were published before or are
+// tracked by a forum state are published as update based on the last known MatchInfo, all others as new match.
+func (s *Server) publishContest(ctx context.Context, id string, known bool) error {
+	matchData, err := s.dotlanClient.GetMatchData(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	var base *matchservice.MatchInfo
+	dotlanForumState, err := s.dbClient.Get(ctx, id)
+	switch {
+	case err == nil:
+		known = true
+		base = dotlanForumState.MatchInfo
+	case !errors.Is(err, mongo.ErrNoDocuments):
+		return err
+	}
+
+	messageType := messagebroker.MessageTypeCreated
+	if known {
+		messageType = messagebroker.MessageTypeUpdated
+	}
+
+	match := matchInfoFromContest(base, id, matchData)
+
+	event := messagebroker.UNWINDIA_MATCH_NEW
+	if match.Finished {
+		event = messagebroker.UNWINDIA_MATCH_FINISHED
+	}
+
+	return s.publisher.PublishMatch(ctx, messageType, event, match)
+}
+
+// matchInfoFromContest returns a copy of the base MatchInfo with the teams and result of the dotlan contest. Players of
+// a team are only replaced by the dotlan team members if the team changed, as the dotlan members lack the game
+// provider ids.
+func matchInfoFromContest(base *matchservice.MatchInfo, id string, matchData *dotlan.MatchData) *matchservice.MatchInfo {
+	match := matchservice.MatchInfo{MsID: id}
+	if base != nil {
+		match = *base
+	}
+
+	match.Team1 = teamFromDotlan(match.Team1, matchData.Team1)
+	match.Team2 = teamFromDotlan(match.Team2, matchData.Team2)
+	match.Finished = matchData.Contest != nil && matchData.Contest.Won != 0
+
+	if match.MatchTitle == "" && match.Team1.Name != "" && match.Team2.Name != "" {
+		match.MatchTitle = fmt.Sprintf("%s vs %s", match.Team1.Name, match.Team2.Name)
+	}
+
+	return &match
+}
+
+func teamFromDotlan(base matchservice.Team, team *dotlan.Team) matchservice.Team {
+	if team == nil {
+		return base
+	}
+
+	id := strconv.FormatUint(uint64(team.Tnid), 10)
+	if base.Id == id && len(base.Players) > 0 {
+		base.Name = team.Name
+		return base
+	}
+
+	players := make([]matchservice.Player, 0, len(team.Members))
+	for _, member := range team.Members {
+		players = append(players, matchservice.Player{
+			Id:   strconv.FormatUint(uint64(member.UserId), 10),
+			Name: member.Nick,
+		})
+	}
+
+	return matchservice.Team{
+		Id:      id,
+		Name:    team.Name,
+		Players: players,
+	}
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"github.com/GSH-LAN/Unwindia_common/src/go/matchservice"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/database"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/dotlan"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/environment"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testContestDotlanClient returns fixed contests, all other methods are not implemented
+type testContestDotlanClient struct {
+	dotlan.DotlanDbClient
+	contests []dotlan.Contest
+}
+
+func (t *testContestDotlanClient) ListContests(_ context.Context) ([]dotlan.Contest, error) {
+	return t.contests, nil
+}
+
+// testContestDatabaseClient stores the contest hashes in memory, all other methods are not implemented
+type testContestDatabaseClient struct {
+	database.DatabaseClient
+	hashes map[string]string
+}
+
+func (t *testContestDatabaseClient) ListContestHashes(_ context.Context) (map[string]string, error) {
+	hashes := make(map[string]string, len(t.hashes))
+	for id, hash := range t.hashes {
+		hashes[id] = hash
+	}
+	return hashes, nil
+}
+
+func (t *testContestDatabaseClient) UpsertContestHash(_ context.Context, id, hash string) error {
+	t.hashes[id] = hash
+	return nil
+}
+
+func TestServer_syncContests_seed(t *testing.T) {
+	contests := []dotlan.Contest{{Tcid: 1001, Won: 11}, {Tcid: 1002}}
+
+	env := &environment.Environment{}
+	env.ContestSyncInterval = time.Second
+
+	dbClient := &testContestDatabaseClient{hashes: map[string]string{}}
+	s := &Server{
+		env:          env,
+		dotlanClient: &testContestDotlanClient{contests: contests},
+		dbClient:     dbClient,
+	}
+
+	// the first pass must store the hashes without publishing, which would fail as no publisher is set
+	if err := s.syncContests(true); err != nil {
+		t.Fatalf("syncContests() error = %v", err)
+	}
+	want := map[string]string{"1001": contests[0].SyncHash(), "1002": contests[1].SyncHash()}
+	if !reflect.DeepEqual(dbClient.hashes, want) {
+		t.Errorf("syncContests() hashes = %v, want %v", dbClient.hashes, want)
+	}
+
+	// unchanged contests are not published on later passes
+	if err := s.syncContests(false); err != nil {
+		t.Fatalf("syncContests() second error = %v", err)
+	}
+}
+
+func TestMatchInfoFromContest(t *testing.T) {
+	matchData := &dotlan.MatchData{
+		Contest: &dotlan.Contest{Tcid: 1001, Won: 11},
+		Team1:   &dotlan.Team{Tnid: 11, Name: "cool-team", Members: []dotlan.TeamMember{{UserId: 21, Nick: "alice"}}},
+		Team2:   &dotlan.Team{Tnid: 12, Name: "nice-team", Members: []dotlan.TeamMember{{UserId: 23, Nick: "carol"}}},
+	}
+
+	tests := []struct {
+		name      string
+		base      *matchservice.MatchInfo
+		matchData *dotlan.MatchData
+		want      *matchservice.MatchInfo
+	}{
+		{
+			name:      "ok-new_match",
+			matchData: matchData,
+			want: &matchservice.MatchInfo{
+				MsID:       "1001",
+				Team1:      matchservice.Team{Id: "11", Name: "cool-team", Players: []matchservice.Player{{Id: "21", Name: "alice"}}},
+				Team2:      matchservice.Team{Id: "12", Name: "nice-team", Players: []matchservice.Player{{Id: "23", Name: "carol"}}},
+				MatchTitle: "cool-team vs nice-team",
+				Finished:   true,
+			},
+		},
+		{
+			name: "ok-known_match_keeps_players",
+			base: &matchservice.MatchInfo{
+				MsID:          "1001",
+				Game:          "csgo",
+				ServerAddress: "10.0.0.1:27015",
+				MatchTitle:    "Final",
+				Team1:         matchservice.Team{Id: "11", Name: "old-name", Players: []matchservice.Player{{Id: "21", Name: "alice", GameProviderID: "steam"}}},
+				Team2:         matchservice.Team{Id: "13", Name: "other-team"},
+			},
+			matchData: matchData,
+			want: &matchservice.MatchInfo{
+				MsID:          "1001",
+				Game:          "csgo",
+				ServerAddress: "10.0.0.1:27015",
+				MatchTitle:    "Final",
+				Team1:         matchservice.Team{Id: "11", Name: "cool-team", Players: []matchservice.Player{{Id: "21", Name: "alice", GameProviderID: "steam"}}},
+				Team2:         matchservice.Team{Id: "12", Name: "nice-team", Players: []matchservice.Player{{Id: "23", Name: "carol"}}},
+				Finished:      true,
+			},
+		},
+		{
+			name:      "ok-teams_not_set",
+			matchData: &dotlan.MatchData{Contest: &dotlan.Contest{Tcid: 1001}},
+			want:      &matchservice.MatchInfo{MsID: "1001"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := matchInfoFromContest(tt.base, "1001", tt.matchData)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matchInfoFromContest() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
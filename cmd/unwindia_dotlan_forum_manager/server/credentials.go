@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/GSH-LAN/Unwindia_common/src/go/matchservice"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/database"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/dotlan"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/environment"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/messagequeue"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/metrics"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/template"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/mongo"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sendCredentials sends the server credentials of the match as dotlan private message to the members of both teams, if
+// the credentials are delivered by PM. Matches without server are skipped. The messages are sent again whenever the
+// rendered message or the recipients change.
+func (s *Server) sendCredentials(event *messagequeue.MatchEvent) error {
+	matchInfo := event.MatchInfo
+	log := log.With().Str("matchId", matchInfo.MsID).Logger()
+
+	if s.env.DotlanCredentialsDelivery != environment.CredentialsDeliveryPM || matchInfo.ServerAddress == "" {
+		return nil
+	}
+
+	cfg := s.config.GetConfig()
+	templateName, tpl, err := template.SelectTemplate(cfg.Templates, template.CredentialsMessageTemplate, s.gameForMatch(matchInfo), event.SubType.String())
+	if err != nil {
+		metrics.TemplateRenderFailures.Inc()
+		return fmt.Errorf("error selecting credentials template: %w", err)
+	}
+	log.Debug().Str("template", templateName).Msg("selected credentials Template")
+
+	dotlanContext, cancel := context.WithTimeout(context.TODO(), time.Second*30)
+	defer cancel()
+
+	matchData, err := s.dotlanClient.GetMatchData(dotlanContext, matchInfo.MsID)
+	if errors.Is(err, dotlan.ErrContestNotFound) {
+		// the forum post is still updated, it never contains the credentials
+		log.Warn().Msg("Contest of match not found within dotlan, credentials can not be sent")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading team members of match for credentials: %w", err)
+	}
+
+	userIds := matchData.UserIds()
+	if len(userIds) == 0 {
+		log.Warn().Msg("Teams of match have no members, credentials can not be sent")
+		return nil
+	}
+
+	// the credentials message is the only text rendered with the unredacted MatchInfo
+	matchContext := &template.MatchContext{MatchInfo: matchInfo, Dotlan: matchData}
+
+	text, err := template.ParseTemplate(tpl, matchContext)
+	if err != nil {
+		metrics.TemplateRenderFailures.Inc()
+		return fmt.Errorf("error parsing credentials template: %w", err)
+	}
+
+	subject, err := s.renderCredentialsSubject(event, matchContext)
+	if err != nil {
+		return err
+	}
+
+	dotlanForumState, err := s.dbClient.Get(context.TODO(), matchInfo.MsID)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return fmt.Errorf("failed to get dotlan forum state: %w", err)
+	}
+	if dotlanForumState == nil {
+		dotlanForumState = &database.DotlanForumStatus{
+			ID:        matchInfo.MsID,
+			CreatedAt: time.Now(),
+		}
+	}
+
+	hash := credentialsHash(userIds, subject, text)
+	if dotlanForumState.CredentialsHash == hash {
+		log.Debug().Msg("Credentials were already sent, skipping")
+		return nil
+	}
+
+	// the hash is stored before sending, so a redelivery of the event never sends the messages twice if storing fails
+	previousHash, previousSentAt := dotlanForumState.CredentialsHash, dotlanForumState.CredentialsSentAt
+	dotlanForumState.CredentialsHash = hash
+	dotlanForumState.CredentialsSentAt = time.Now()
+
+	if err = s.dbClient.Upsert(context.TODO(), dotlanForumState); err != nil {
+		return fmt.Errorf("error upserting dotlanForumState: %w", err)
+	}
+
+	if err = s.dotlanClient.SendPrivateMessages(dotlanContext, userIds, subject, text); err != nil {
+		dotlanForumState.CredentialsHash = previousHash
+		dotlanForumState.CredentialsSentAt = previousSentAt
+		if upsertErr := s.dbClient.Upsert(context.TODO(), dotlanForumState); upsertErr != nil {
+			log.Error().Err(upsertErr).Msg("Error resetting credentials hash, credentials are not sent again until the message changes")
+		}
+		return fmt.Errorf("error sending credentials: %w", err)
+	}
+	log.Info().Int("recipients", len(userIds)).Msg("Sent credentials as private messages")
+
+	return nil
+}
+
+// renderCredentialsSubject renders the subject of the credentials message. Without a subject template the MatchTitle is
+// used.
+func (s *Server) renderCredentialsSubject(event *messagequeue.MatchEvent, matchContext *template.MatchContext) (string, error) {
+	cfg := s.config.GetConfig()
+	_, tpl, err := template.SelectTemplate(cfg.Templates, template.CredentialsSubjectTemplate, s.gameForMatch(event.MatchInfo), event.SubType.String())
+	if errors.Is(err, template.ErrTemplateNotFound) {
+		return defaultCredentialsSubject(event.MatchInfo), nil
+	}
+	if err != nil {
+		metrics.TemplateRenderFailures.Inc()
+		return "", fmt.Errorf("error selecting credentials subject template: %w", err)
+	}
+
+	subject, err := template.ParseTitle(tpl, matchContext)
+	if err != nil {
+		metrics.TemplateRenderFailures.Inc()
+		return "", fmt.Errorf("error parsing credentials subject template: %w", err)
+	}
+
+	return subject, nil
+}
+
+func defaultCredentialsSubject(matchInfo *matchservice.MatchInfo) string {
+	if matchInfo.MatchTitle == "" {
+		return "Server credentials"
+	}
+	return "Server credentials: " + matchInfo.MatchTitle
+}
+
+// credentialsHash returns the hash of the credentials message and its recipients
+func credentialsHash(userIds []uint, subject, text string) string {
+	recipients := make([]string, 0, len(userIds))
+	for _, userId := range userIds {
+		recipients = append(recipients, strconv.FormatUint(uint64(userId), 10))
+	}
+
+	return contentHash(strings.Join(recipients, ",") + "\n" + subject + "\n" + text)
+}
+
+// credentialsPolicy returns the redaction policy for the forum templates. If the credentials are delivered by PM, the
+// credential fields are masked in every forum.
+func credentialsPolicy(env *environment.Environment) (*template.RedactionPolicy, error) {
+	if env.DotlanCredentialsDelivery != environment.CredentialsDeliveryPM {
+		return template.NewRedactionPolicy(env.TemplateSensitiveFields, env.DotlanPrivateForumIds)
+	}
+
+	sensitiveFields := append([]string{}, env.TemplateSensitiveFields...)
+	return template.NewRedactionPolicy(append(sensitiveFields, template.CredentialFields...), nil)
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"context"
+	"errors"
+	unwindiaConfig "github.com/GSH-LAN/Unwindia_common/src/go/config"
+	"github.com/GSH-LAN/Unwindia_common/src/go/matchservice"
+	"github.com/GSH-LAN/Unwindia_common/src/go/messagebroker"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/config"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/database"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/dotlan"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/environment"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/messagequeue"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/template"
+	"testing"
+)
+
+// testCredentialsDotlanClient returns the teams of a match and records the sent private messages, all other methods
+// are not implemented
+type testCredentialsDotlanClient struct {
+	dotlan.DotlanDbClient
+	sendErr error
+	sent    int
+}
+
+func (t *testCredentialsDotlanClient) GetMatchData(_ context.Context, _ string) (*dotlan.MatchData, error) {
+	return &dotlan.MatchData{
+		Contest: &dotlan.Contest{Tcid: 1001},
+		Team1:   &dotlan.Team{Tnid: 11, Members: []dotlan.TeamMember{{UserId: 21, Nick: "alice"}}},
+		Team2:   &dotlan.Team{Tnid: 12, Members: []dotlan.TeamMember{{UserId: 23, Nick: "carol"}}},
+	}, nil
+}
+
+func (t *testCredentialsDotlanClient) SendPrivateMessages(_ context.Context, _ []uint, _, _ string) error {
+	if t.sendErr != nil {
+		return t.sendErr
+	}
+	t.sent++
+	return nil
+}
+
+func TestServer_sendCredentials(t *testing.T) {
+	errUpsert := errors.New("upsert failed")
+	errSend := errors.New("send failed")
+
+	tests := []struct {
+		name      string
+		upsertErr error
+		sendErr   error
+		wantErr   error
+		wantSent  int
+		wantHash  bool
+	}{
+		{
+			name:     "ok-sent",
+			wantSent: 1,
+			wantHash: true,
+		},
+		{
+			name:      "err-upsert_failed",
+			upsertErr: errUpsert,
+			wantErr:   errUpsert,
+		},
+		{
+			name:    "err-send_failed",
+			sendErr: errSend,
+			wantErr: errSend,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := &environment.Environment{}
+			env.DotlanCredentialsDelivery = environment.CredentialsDeliveryPM
+
+			dotlanClient := &testCredentialsDotlanClient{sendErr: tt.sendErr}
+			dbClient := &testForumDatabaseClient{state: &database.DotlanForumStatus{ID: "1001"}, upsertErr: tt.upsertErr}
+			s := &Server{
+				env: env,
+				config: testConfigClient{config: &config.Config{
+					Config: unwindiaConfig.Config{
+						Templates: map[string]string{template.CredentialsMessageTemplate + ".gohtml": "{{.ServerAddress}} {{.ServerPassword}}"},
+					},
+				}},
+				dotlanClient: dotlanClient,
+				dbClient:     dbClient,
+			}
+			event := &messagequeue.MatchEvent{
+				SubType:   messagebroker.UNWINDIA_MATCH_READY_ALL,
+				MatchInfo: &matchservice.MatchInfo{MsID: "1001", ServerAddress: "10.0.0.1:27015", ServerPassword: "password"},
+			}
+
+			err := s.sendCredentials(event)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("sendCredentials() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if dotlanClient.sent != tt.wantSent {
+				t.Errorf("sendCredentials() sent = %v, want %v", dotlanClient.sent, tt.wantSent)
+			}
+			if gotHash := dbClient.state.CredentialsHash != ""; gotHash != tt.wantHash {
+				t.Errorf("sendCredentials() stored hash = %v, want %v", gotHash, tt.wantHash)
+			}
+
+			// the redelivered event sends the messages exactly once in total
+			dotlanClient.sendErr = nil
+			dbClient.upsertErr = nil
+			if err = s.sendCredentials(event); err != nil {
+				t.Fatalf("sendCredentials() redelivery error = %v", err)
+			}
+			if dotlanClient.sent != 1 {
+				t.Errorf("sendCredentials() sent after redelivery = %v, want 1", dotlanClient.sent)
+			}
+		})
+	}
+}
+
+func TestCredentialsPolicy(t *testing.T) {
+	matchInfo := &matchservice.MatchInfo{ServerPassword: "password", ServerPasswordMgmt: "secret"}
+
+	tests := []struct {
+		name             string
+		delivery         string
+		forumId          int
+		wantPassword     string
+		wantPasswordMgmt string
+	}{
+		{
+			name:             "ok-forum_public",
+			delivery:         environment.CredentialsDeliveryForum,
+			forumId:          9,
+			wantPassword:     "password",
+			wantPasswordMgmt: template.RedactedValue,
+		},
+		{
+			name:             "ok-forum_private",
+			delivery:         environment.CredentialsDeliveryForum,
+			forumId:          42,
+			wantPassword:     "password",
+			wantPasswordMgmt: "secret",
+		},
+		{
+			name:             "ok-pm_private",
+			delivery:         environment.CredentialsDeliveryPM,
+			forumId:          42,
+			wantPassword:     template.RedactedValue,
+			wantPasswordMgmt: template.RedactedValue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := &environment.Environment{}
+			env.DotlanCredentialsDelivery = tt.delivery
+			env.TemplateSensitiveFields = template.DefaultSensitiveFields
+			env.DotlanPrivateForumIds = []int{42}
+
+			policy, err := credentialsPolicy(env)
+			if err != nil {
+				t.Fatalf("credentialsPolicy() error = %v", err)
+			}
+
+			got := policy.MatchInfoForForum(matchInfo, tt.forumId)
+			if got.ServerPassword != tt.wantPassword || got.ServerPasswordMgmt != tt.wantPasswordMgmt {
+				t.Errorf("MatchInfoForForum() passwords = %v, %v, want %v, %v", got.ServerPassword, got.ServerPasswordMgmt, tt.wantPassword, tt.wantPasswordMgmt)
+			}
+		})
+	}
+}
+
+func TestCredentialsHash(t *testing.T) {
+	hash := credentialsHash([]uint{21, 22}, "subject", "text")
+
+	tests := []struct {
+		name     string
+		userIds  []uint
+		subject  string
+		text     string
+		wantSame bool
+	}{
+		{
+			name:     "ok-unchanged",
+			userIds:  []uint{21, 22},
+			subject:  "subject",
+			text:     "text",
+			wantSame: true,
+		},
+		{
+			name:    "ok-recipient_added",
+			userIds: []uint{21, 22, 23},
+			subject: "subject",
+			text:    "text",
+		},
+		{
+			name:    "ok-text_changed",
+			userIds: []uint{21, 22},
+			subject: "subject",
+			text:    "new password",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := credentialsHash(tt.userIds, tt.subject, tt.text); (got == hash) != tt.wantSame {
+				t.Errorf("credentialsHash() same = %v, want %v", got == hash, tt.wantSame)
+			}
+		})
+	}
+}
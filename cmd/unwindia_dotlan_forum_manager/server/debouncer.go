@@ -0,0 +1,92 @@
+package server
+
+import (
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/messagequeue"
+	"sync"
+	"time"
+)
+
+// debouncer coalesces bursts of events of the same match. Events are held back until no newer event of the match
+// arrived within the window, but at most for maxDelay after the first held back event, so a match is never starved by
+// a steady stream of updates. A window of zero disables coalescing.
+type debouncer struct {
+	window    time.Duration
+	maxDelay  time.Duration
+	flush     func(key string, events []*messagequeue.MatchEvent)
+	lock      sync.Mutex
+	pending   map[string]*pendingEvents
+	now       func() time.Time
+	afterFunc func(d time.Duration, f func()) timer
+}
+
+// timer is the part of time.Timer used by the debouncer, so tests can replace the clock
+type timer interface {
+	Reset(d time.Duration) bool
+}
+
+type pendingEvents struct {
+	events []*messagequeue.MatchEvent
+	first  time.Time
+	timer  timer
+}
+
+func newDebouncer(window, maxDelay time.Duration, flush func(key string, events []*messagequeue.MatchEvent)) *debouncer {
+	if maxDelay < window {
+		maxDelay = window
+	}
+
+	return &debouncer{
+		window:   window,
+		maxDelay: maxDelay,
+		flush:    flush,
+		pending:  make(map[string]*pendingEvents),
+		now:      time.Now,
+		afterFunc: func(d time.Duration, f func()) timer {
+			return time.AfterFunc(d, f)
+		},
+	}
+}
+
+// Push adds the event of the match with the given key to the pending events and (re)starts the window
+func (d *debouncer) Push(key string, event *messagequeue.MatchEvent) {
+	if d.window <= 0 {
+		d.flush(key, []*messagequeue.MatchEvent{event})
+		return
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	p, ok := d.pending[key]
+	if !ok {
+		p = &pendingEvents{first: d.now()}
+		p.timer = d.afterFunc(d.window, func() {
+			d.fire(key, p)
+		})
+		d.pending[key] = p
+	} else {
+		delay := d.window
+		if remaining := d.maxDelay - d.now().Sub(p.first); remaining < delay {
+			delay = remaining
+		}
+		if delay < 0 {
+			delay = 0
+		}
+		p.timer.Reset(delay)
+	}
+
+	p.events = append(p.events, event)
+}
+
+func (d *debouncer) fire(key string, p *pendingEvents) {
+	d.lock.Lock()
+	if d.pending[key] != p {
+		// already flushed by an earlier run of the timer
+		d.lock.Unlock()
+		return
+	}
+	delete(d.pending, key)
+	d.lock.Unlock()
+
+	d.flush(key, p.events)
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"github.com/GSH-LAN/Unwindia_common/src/go/matchservice"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/messagequeue"
+	"testing"
+	"time"
+)
+
+type flushed struct {
+	key    string
+	events []*messagequeue.MatchEvent
+}
+
+// testClock is a manually advanced clock, whose timers fire synchronously within Advance
+type testClock struct {
+	now    time.Time
+	timers []*testTimer
+}
+
+type testTimer struct {
+	clock    *testClock
+	deadline time.Time
+	fn       func()
+	active   bool
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) AfterFunc(d time.Duration, f func()) timer {
+	t := &testTimer{clock: c, fn: f}
+	t.Reset(d)
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance moves the clock forward and fires all timers which are due
+func (c *testClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+	for _, t := range c.timers {
+		if t.active && !t.deadline.After(c.now) {
+			t.active = false
+			t.fn()
+		}
+	}
+}
+
+func (t *testTimer) Reset(d time.Duration) bool {
+	wasActive := t.active
+	t.deadline = t.clock.now.Add(d)
+	t.active = true
+	return wasActive
+}
+
+func TestDebouncer_Push(t *testing.T) {
+	tests := []struct {
+		name       string
+		window     time.Duration
+		maxDelay   time.Duration
+		pushes     int
+		pushEvery  time.Duration
+		wantFlushs []int
+	}{
+		{
+			name:       "disabled",
+			window:     0,
+			pushes:     3,
+			wantFlushs: []int{1, 1, 1},
+		},
+		{
+			name:       "burst_within_window",
+			window:     50 * time.Millisecond,
+			maxDelay:   time.Second,
+			pushes:     3,
+			pushEvery:  5 * time.Millisecond,
+			wantFlushs: []int{3},
+		},
+		{
+			name:       "pause_longer_than_window",
+			window:     50 * time.Millisecond,
+			maxDelay:   time.Second,
+			pushes:     3,
+			pushEvery:  60 * time.Millisecond,
+			wantFlushs: []int{1, 1, 1},
+		},
+		{
+			name:       "max_delay_reached",
+			window:     50 * time.Millisecond,
+			maxDelay:   100 * time.Millisecond,
+			pushes:     10,
+			pushEvery:  20 * time.Millisecond,
+			wantFlushs: []int{5, 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &testClock{now: time.Date(2022, 5, 1, 18, 0, 0, 0, time.UTC)}
+
+			var got []flushed
+			d := newDebouncer(tt.window, tt.maxDelay, func(key string, events []*messagequeue.MatchEvent) {
+				got = append(got, flushed{key: key, events: events})
+			})
+			d.now = clock.Now
+			d.afterFunc = clock.AfterFunc
+
+			for i := 0; i < tt.pushes; i++ {
+				d.Push("match", &messagequeue.MatchEvent{MatchInfo: &matchservice.MatchInfo{MsID: "match", PlayerAmount: uint(i)}})
+				clock.Advance(tt.pushEvery)
+			}
+			clock.Advance(tt.maxDelay)
+
+			if len(got) != len(tt.wantFlushs) {
+				t.Fatalf("Push() flushs = %v, want %v", len(got), len(tt.wantFlushs))
+			}
+
+			next := 0
+			for i, f := range got {
+				if f.key != "match" || len(f.events) != tt.wantFlushs[i] {
+					t.Errorf("Push() flush %v = %v with %v events, want match with %v events", i, f.key, len(f.events), tt.wantFlushs[i])
+				}
+				// events are flushed completely and in order
+				for _, event := range f.events {
+					if event.MatchInfo.PlayerAmount != uint(next) {
+						t.Errorf("Push() flushed event = %v, want %v", event.MatchInfo.PlayerAmount, next)
+					}
+					next++
+				}
+			}
+			if next != tt.pushes {
+				t.Errorf("Push() flushed events = %v, want %v", next, tt.pushes)
+			}
+		})
+	}
+}
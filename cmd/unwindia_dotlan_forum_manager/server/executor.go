@@ -0,0 +1,70 @@
+package server
+
+import (
+	"github.com/gammazero/workerpool"
+	"sync"
+)
+
+// keyedExecutor runs tasks on a workerpool. Tasks with the same key are executed strictly in the order they were
+// submitted, one after another, while tasks with different keys run in parallel up to the size of the workerpool.
+type keyedExecutor struct {
+	workerpool *workerpool.WorkerPool
+	lock       sync.Mutex
+	queues     map[string][]func()
+}
+
+func newKeyedExecutor(wp *workerpool.WorkerPool) *keyedExecutor {
+	return &keyedExecutor{
+		workerpool: wp,
+		queues:     make(map[string][]func()),
+	}
+}
+
+// Submit enqueues the task for the given key. It does not wait for the task to be executed.
+func (k *keyedExecutor) Submit(key string, task func()) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if queue, running := k.queues[key]; running {
+		k.queues[key] = append(queue, task)
+		return
+	}
+
+	k.queues[key] = nil
+	k.workerpool.Submit(func() {
+		k.run(key, task)
+	})
+}
+
+// run executes the task and submits the next queued task of the same key afterwards, so other keys get a chance to
+// run in between
+func (k *keyedExecutor) run(key string, task func()) {
+	task()
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	queue := k.queues[key]
+	if len(queue) == 0 {
+		delete(k.queues, key)
+		return
+	}
+
+	next := queue[0]
+	k.queues[key] = queue[1:]
+	k.workerpool.Submit(func() {
+		k.run(key, next)
+	})
+}
+
+// Backlog returns the number of tasks which wait for an earlier task of the same key
+func (k *keyedExecutor) Backlog() int {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	backlog := 0
+	for _, queue := range k.queues {
+		backlog += len(queue)
+	}
+	return backlog
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"github.com/gammazero/workerpool"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKeyedExecutor_SubmitOrderPerKey(t *testing.T) {
+	wp := workerpool.New(4)
+	defer wp.StopWait()
+
+	executor := newKeyedExecutor(wp)
+
+	var lock sync.Mutex
+	results := make(map[string][]int)
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		for _, key := range []string{"a", "b", "c"} {
+			i, key := i, key
+			wg.Add(1)
+			executor.Submit(key, func() {
+				defer wg.Done()
+				// sleep a bit for early tasks, so a wrong ordering would become visible
+				if i%5 == 0 {
+					time.Sleep(time.Millisecond)
+				}
+				lock.Lock()
+				results[key] = append(results[key], i)
+				lock.Unlock()
+			})
+		}
+	}
+	wg.Wait()
+
+	for key, values := range results {
+		for i, value := range values {
+			if value != i {
+				t.Errorf("Submit() key %s executed task %d at position %d", key, value, i)
+				break
+			}
+		}
+	}
+}
+
+func TestKeyedExecutor_SubmitParallelKeys(t *testing.T) {
+	wp := workerpool.New(2)
+	defer wp.StopWait()
+
+	executor := newKeyedExecutor(wp)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	executor.Submit("a", func() {
+		close(started)
+		<-release
+	})
+
+	done := make(chan struct{})
+	executor.Submit("b", func() {
+		close(done)
+	})
+
+	<-started
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("Submit() task of key b was blocked by key a")
+	}
+	close(release)
+}
+
+func TestKeyedExecutor_Backlog(t *testing.T) {
+	wp := workerpool.New(2)
+	defer wp.StopWait()
+
+	executor := newKeyedExecutor(wp)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+
+	wg.Add(4)
+	executor.Submit("a", func() {
+		defer wg.Done()
+		close(started)
+		<-release
+	})
+	<-started
+
+	// the tasks of key a wait for the running task, the task of key b runs in parallel
+	executor.Submit("a", wg.Done)
+	executor.Submit("a", wg.Done)
+	executor.Submit("b", wg.Done)
+
+	if got := executor.Backlog(); got != 2 {
+		t.Errorf("Backlog() = %v, want 2", got)
+	}
+
+	close(release)
+	wg.Wait()
+
+	if got := executor.Backlog(); got != 0 {
+		t.Errorf("Backlog() after run = %v, want 0", got)
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"github.com/GSH-LAN/Unwindia_common/src/go/messagebroker"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/messagequeue"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+// eventHandler executes a single forum action for a match event
+type eventHandler func(event *messagequeue.MatchEvent) error
+
+// forumAction is a named eventHandler, so the actions of coalesced events can be merged without running one twice
+type forumAction struct {
+	name    string
+	handler eventHandler
+}
+
+// registerHandlers registers the forum actions which are executed in order for each match event subtype. Subtypes
+// without registered handlers are skipped.
+func (s *Server) registerHandlers() {
+	sendCredentials := forumAction{name: "send_credentials", handler: s.sendCredentials}
+	updateForumPost := forumAction{name: "update_forum_post", handler: s.updateForumPost}
+	closeForumThread := forumAction{name: "close_forum_thread", handler: s.closeForumThread}
+	archiveForumThread := forumAction{name: "archive_forum_thread", handler: s.archiveForumThread}
+
+	s.handlers = map[messagebroker.MatchEvent][]forumAction{
+		messagebroker.UNWINDIA_MATCH_NEW:       {sendCredentials, updateForumPost},
+		messagebroker.UNWINDIA_MATCH_READY_A:   {sendCredentials, updateForumPost},
+		messagebroker.UNWINDIA_MATCH_READY_B:   {sendCredentials, updateForumPost},
+		messagebroker.UNWINDIA_MATCH_READY_ALL: {sendCredentials, updateForumPost},
+		messagebroker.UNWINDIA_MATCH_FINISHED:  {updateForumPost, closeForumThread, archiveForumThread},
+	}
+}
+
+// matchEventsHandler handles a batch of coalesced events of the same match. The forum is written once from the latest
+// event, since its MatchInfo supersedes the ones of the earlier events, but the actions registered for the subtypes of
+// all events are executed, so e.g. a finished match is closed even if a later event was coalesced with it. The result
+// is acknowledged for all events.
+func (s *Server) matchEventsHandler(events []*messagequeue.MatchEvent) {
+	latest := events[len(events)-1]
+	if len(events) > 1 {
+		metrics.MatchEventsCoalesced.Add(float64(len(events) - 1))
+		log.Debug().Str("matchId", latest.MatchInfo.MsID).Int("coalesced", len(events)-1).Msg("Coalesced match events")
+	}
+
+	err := s.dispatchEvents(events)
+	for _, event := range events {
+		if err != nil {
+			event.Nack(err)
+		} else {
+			event.Ack()
+		}
+	}
+}
+
+// dispatchEvents runs the actions registered for the subtypes of the events with the latest event. Every action is run
+// once, in the order of its first registration.
+func (s *Server) dispatchEvents(events []*messagequeue.MatchEvent) error {
+	latest := events[len(events)-1]
+
+	var handlers []eventHandler
+	seen := make(map[string]bool)
+	for _, event := range events {
+		log := log.With().Str("matchId", event.MatchInfo.MsID).Str("subType", event.SubType.String()).Logger()
+
+		actions, ok := s.handlers[event.SubType]
+		if !ok || len(actions) == 0 {
+			log.Warn().Msg("No handlers registered for subtype, skipping event")
+			continue
+		}
+
+		if !s.updateDotlanOnEvent(event.SubType) {
+			log.Debug().Msg("Updating dotlan is not enabled for subtype, skipping event")
+			continue
+		}
+
+		for _, action := range actions {
+			if !seen[action.name] {
+				seen[action.name] = true
+				handlers = append(handlers, action.handler)
+			}
+		}
+	}
+
+	if len(handlers) == 0 {
+		return nil
+	}
+
+	return s.handleEvent(latest, handlers...)
+}
+
+// eventHandlers returns the handlers of the actions
+func eventHandlers(actions []forumAction) []eventHandler {
+	handlers := make([]eventHandler, 0, len(actions))
+	for _, action := range actions {
+		handlers = append(handlers, action.handler)
+	}
+	return handlers
+}
+
+// updateDotlanOnEvent checks if the subtype is enabled by the UpdateDotlanOnEvents config. All subtypes are enabled if
+// the list is empty.
+func (s *Server) updateDotlanOnEvent(subType messagebroker.MatchEvent) bool {
+	enabledEvents := s.config.GetConfig().UpdateDotlanOnEvents
+	if len(enabledEvents) == 0 {
+		return true
+	}
+
+	for _, enabledEvent := range enabledEvents {
+		if enabledEvent == subType {
+			return true
+		}
+	}
+
+	return false
+}
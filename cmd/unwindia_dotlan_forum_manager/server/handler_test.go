@@ -0,0 +1,117 @@
+package server
+
+import (
+	"errors"
+	unwindiaConfig "github.com/GSH-LAN/Unwindia_common/src/go/config"
+	"github.com/GSH-LAN/Unwindia_common/src/go/matchservice"
+	"github.com/GSH-LAN/Unwindia_common/src/go/messagebroker"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/config"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/database"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/messagequeue"
+	"reflect"
+	"testing"
+)
+
+func TestServer_dispatchEvents(t *testing.T) {
+	errHandler := errors.New("handler failed")
+
+	tests := []struct {
+		name          string
+		subTypes      []messagebroker.MatchEvent
+		enabledEvents []messagebroker.MatchEvent
+		wantCalls     []string
+		wantErr       error
+	}{
+		{
+			name:      "ok-registered_subtype",
+			subTypes:  []messagebroker.MatchEvent{messagebroker.UNWINDIA_MATCH_NEW},
+			wantCalls: []string{"credentials", "post"},
+		},
+		{
+			name:      "ok-other_actions_per_subtype",
+			subTypes:  []messagebroker.MatchEvent{messagebroker.UNWINDIA_MATCH_FINISHED},
+			wantCalls: []string{"post", "close"},
+		},
+		{
+			name:     "ok-unregistered_subtype_skipped",
+			subTypes: []messagebroker.MatchEvent{messagebroker.UNWINDIA_MATCH_READY_A},
+		},
+		{
+			name:          "ok-enabled_subtype",
+			subTypes:      []messagebroker.MatchEvent{messagebroker.UNWINDIA_MATCH_NEW},
+			enabledEvents: []messagebroker.MatchEvent{messagebroker.UNWINDIA_MATCH_NEW},
+			wantCalls:     []string{"credentials", "post"},
+		},
+		{
+			name:          "ok-disabled_subtype_skipped",
+			subTypes:      []messagebroker.MatchEvent{messagebroker.UNWINDIA_MATCH_NEW},
+			enabledEvents: []messagebroker.MatchEvent{messagebroker.UNWINDIA_MATCH_FINISHED},
+		},
+		{
+			name:      "ok-coalesced_actions_merged",
+			subTypes:  []messagebroker.MatchEvent{messagebroker.UNWINDIA_MATCH_NEW, messagebroker.UNWINDIA_MATCH_FINISHED},
+			wantCalls: []string{"credentials", "post", "close"},
+		},
+		{
+			name:      "ok-coalesced_unregistered_subtype",
+			subTypes:  []messagebroker.MatchEvent{messagebroker.UNWINDIA_MATCH_FINISHED, messagebroker.UNWINDIA_MATCH_READY_A},
+			wantCalls: []string{"post", "close"},
+		},
+		{
+			name:          "ok-coalesced_disabled_subtype",
+			subTypes:      []messagebroker.MatchEvent{messagebroker.UNWINDIA_MATCH_NEW, messagebroker.UNWINDIA_MATCH_FINISHED},
+			enabledEvents: []messagebroker.MatchEvent{messagebroker.UNWINDIA_MATCH_FINISHED},
+			wantCalls:     []string{"post", "close"},
+		},
+		{
+			name:      "err-handler_failed",
+			subTypes:  []messagebroker.MatchEvent{messagebroker.UNWINDIA_MATCH_READY_B},
+			wantCalls: []string{"failing"},
+			wantErr:   errHandler,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			action := func(name string, err error) forumAction {
+				return forumAction{name: name, handler: func(event *messagequeue.MatchEvent) error {
+					// the actions of all coalesced events are run with the latest event
+					if latest := tt.subTypes[len(tt.subTypes)-1]; event.SubType != latest {
+						t.Errorf("dispatchEvents() %v called with subtype %v, want %v", name, event.SubType, latest)
+					}
+					calls = append(calls, name)
+					return err
+				}}
+			}
+
+			dbClient := &testForumDatabaseClient{state: &database.DotlanForumStatus{ID: "1001"}}
+			s := &Server{
+				config: testConfigClient{config: &config.Config{
+					Config: unwindiaConfig.Config{UpdateDotlanOnEvents: tt.enabledEvents},
+				}},
+				handlers: map[messagebroker.MatchEvent][]forumAction{
+					messagebroker.UNWINDIA_MATCH_NEW:      {action("credentials", nil), action("post", nil)},
+					messagebroker.UNWINDIA_MATCH_FINISHED: {action("post", nil), action("close", nil)},
+					messagebroker.UNWINDIA_MATCH_READY_B:  {action("failing", errHandler), action("post", nil)},
+				},
+				dbClient: dbClient,
+			}
+
+			var events []*messagequeue.MatchEvent
+			for _, subType := range tt.subTypes {
+				events = append(events, &messagequeue.MatchEvent{SubType: subType, MatchInfo: &matchservice.MatchInfo{MsID: "1001"}})
+			}
+
+			err := s.dispatchEvents(events)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("dispatchEvents() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("dispatchEvents() calls = %v, want %v", calls, tt.wantCalls)
+			}
+			if tt.wantErr != nil && dbClient.state.LastError != tt.wantErr.Error() {
+				t.Errorf("dispatchEvents() last error = %v, want %v", dbClient.state.LastError, tt.wantErr)
+			}
+		})
+	}
+}
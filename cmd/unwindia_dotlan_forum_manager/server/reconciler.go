@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"github.com/GSH-LAN/Unwindia_common/src/go/messagebroker"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/database"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/messagequeue"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/metrics"
+	"github.com/rs/zerolog/log"
+	"sync"
+	"time"
+)
+
+// startReconciler periodically checks that the forum threads and posts of all stored forum states still exist within
+// dotlan and recreates missing ones
+func (s *Server) startReconciler() {
+	if s.env.ProcessInterval <= 0 {
+		log.Info().Msg("Reconciling forum states is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.env.ProcessInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.reconcile()
+			}
+		}
+	}()
+}
+
+// reconcile checks all stored forum states and waits until every check is done, so runs never overlap
+func (s *Server) reconcile() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.env.ProcessInterval)
+	defer cancel()
+
+	resultChan := make(chan database.Result, 1)
+	s.dbClient.List(ctx, nil, resultChan)
+	result := <-resultChan
+	if result.Error != nil {
+		log.Error().Err(result.Error).Msg("Error listing forum states for reconciling")
+		return
+	}
+
+	log.Debug().Int("entries", len(result.Result)).Msg("Reconciling forum states")
+
+	var wg sync.WaitGroup
+	for _, entry := range result.Result {
+		// entries without thread or post failed on creation and are retried by redelivery of their event
+		if !entry.HasForumPost() {
+			continue
+		}
+
+		id := entry.ID
+		wg.Add(1)
+		s.executor.Submit(id, func() {
+			defer wg.Done()
+			s.reconcileEntry(id)
+		})
+	}
+	wg.Wait()
+}
+
+// reconcileEntry checks a single forum state against dotlan and writes the forum post again if the thread or post is
+// missing
+func (s *Server) reconcileEntry(id string) {
+	log := log.With().Str("matchId", id).Logger()
+
+	dotlanForumState, err := s.dbClient.Get(context.TODO(), id)
+	if err != nil {
+		log.Error().Err(err).Msg("Error getting forum state for reconciling")
+		return
+	}
+
+	if !dotlanForumState.HasForumPost() {
+		return
+	}
+
+	dotlanContext, cancel := context.WithTimeout(context.TODO(), time.Second*30)
+	defer cancel()
+
+	threadExists, postExists, err := s.dotlanClient.CheckForumPost(dotlanContext, dotlanForumState.DotlanForumThreadID, dotlanForumState.DotlanForumPostID)
+	if err != nil {
+		log.Error().Err(err).Msg("Error checking forum post for reconciling")
+		return
+	}
+
+	switch {
+	case !threadExists:
+		s.recordDrift(dotlanForumState, metrics.DriftThreadMissing)
+		dotlanForumState.DotlanForumThreadID = 0
+		dotlanForumState.DotlanForumPostID = 0
+		dotlanForumState.DotlanResultPostID = 0
+		dotlanForumState.DotlanForumID = 0
+		dotlanForumState.ThreadClosed = false
+		dotlanForumState.ThreadArchived = false
+	case !postExists:
+		s.recordDrift(dotlanForumState, metrics.DriftPostMissing)
+		dotlanForumState.DotlanForumPostID = 0
+	default:
+		return
+	}
+
+	dotlanForumState.ContentHash = ""
+	if err = s.dbClient.Upsert(context.TODO(), dotlanForumState); err != nil {
+		log.Error().Err(err).Msg("Error resetting forum state for repair")
+		return
+	}
+
+	if dotlanForumState.MatchInfo == nil {
+		log.Warn().Msg("Forum state has no MatchInfo, forum post can not be repaired until the next event of the match")
+		return
+	}
+
+	event := messagequeue.MatchEvent{
+		SubType:   messagebroker.EventsValue[dotlanForumState.LastEvent],
+		MatchInfo: dotlanForumState.MatchInfo,
+	}
+	handlers := eventHandlers(s.handlers[event.SubType])
+	if len(handlers) == 0 {
+		handlers = []eventHandler{s.updateForumPost}
+	}
+	if err = s.handleEvent(&event, handlers...); err != nil {
+		log.Error().Err(err).Msg("Error repairing forum post")
+		return
+	}
+
+	log.Info().Msg("Repaired forum post")
+}
+
+// recordDrift reports a difference between the forum state and the dotlan forum. The state is updated in place and has
+// to be stored by the caller.
+func (s *Server) recordDrift(dotlanForumState *database.DotlanForumStatus, kind string) {
+	log.Warn().
+		Str("matchId", dotlanForumState.ID).
+		Int("threadId", dotlanForumState.DotlanForumThreadID).
+		Int("postId", dotlanForumState.DotlanForumPostID).
+		Str("drift", kind).
+		Msg("Dotlan forum differs from stored forum state")
+
+	metrics.ForumDrift.WithLabelValues(kind).Inc()
+	dotlanForumState.LastDrift = kind
+	dotlanForumState.LastDriftAt = time.Now()
+}
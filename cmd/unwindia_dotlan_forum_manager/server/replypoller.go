@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/command"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/database"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/dotlan"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/messagequeue"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/metrics"
+	"github.com/rs/zerolog/log"
+	"sync"
+	"time"
+)
+
+// startReplyPoller periodically checks the open match threads for new replies of dotlan users and publishes them as
+// match comments on the message queue
+func (s *Server) startReplyPoller() {
+	if s.env.ReplyPollInterval <= 0 {
+		log.Info().Msg("Polling match threads for replies is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.env.ReplyPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.pollReplies()
+			}
+		}
+	}()
+}
+
+// pollReplies checks all open match threads and waits until every check is done, so runs never overlap
+func (s *Server) pollReplies() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.env.ReplyPollInterval)
+	defer cancel()
+
+	resultChan := make(chan database.Result, 1)
+	s.dbClient.List(ctx, nil, resultChan)
+	result := <-resultChan
+	if result.Error != nil {
+		log.Error().Err(result.Error).Msg("Error listing forum states for polling replies")
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, entry := range result.Result {
+		if entry.DotlanForumThreadID <= 0 || entry.ThreadClosed {
+			continue
+		}
+
+		id := entry.ID
+		wg.Add(1)
+		s.executor.Submit(id, func() {
+			defer wg.Done()
+			s.pollRepliesOfMatch(id)
+		})
+	}
+	wg.Wait()
+}
+
+// pollRepliesOfMatch publishes the replies written since the last seen post of the match thread. The last seen post is
+// stored after every published reply, so a failing publish is retried with the next poll without duplicating replies.
+func (s *Server) pollRepliesOfMatch(id string) {
+	log := log.With().Str("matchId", id).Logger()
+
+	dotlanForumState, err := s.dbClient.Get(context.TODO(), id)
+	if err != nil {
+		log.Error().Err(err).Msg("Error getting forum state for polling replies")
+		return
+	}
+
+	if dotlanForumState.DotlanForumThreadID <= 0 || dotlanForumState.ThreadClosed {
+		return
+	}
+
+	// replies older than the first post of the bot were written before the match was tracked
+	afterPostId := dotlanForumState.LastSeenPostID
+	if dotlanForumState.DotlanForumPostID > afterPostId {
+		afterPostId = dotlanForumState.DotlanForumPostID
+	}
+
+	dotlanContext, cancel := context.WithTimeout(context.TODO(), time.Second*30)
+	defer cancel()
+
+	replies, err := s.dotlanClient.ListForumReplies(dotlanContext, dotlanForumState.DotlanForumThreadID, afterPostId)
+	if err != nil {
+		log.Error().Err(err).Msg("Error listing replies of forum thread")
+		return
+	}
+
+	// the teams are only read from dotlan once a reply contains a command
+	var matchData *dotlan.MatchData
+	for _, reply := range replies {
+		var matchCommand *messagequeue.MatchCommand
+		cmd, err := command.Parse(reply.Pagetext)
+		switch {
+		case err == nil:
+			if matchData == nil {
+				if matchData, err = s.teamsOfMatch(dotlanContext, dotlanForumState.ID); err != nil {
+					log.Error().Err(err).Msg("Error getting teams of match for checking commands")
+					return
+				}
+			}
+			matchCommand = commandOfTeamMember(dotlanForumState.ID, &reply, cmd, matchData)
+		case !errors.Is(err, command.ErrNoCommand):
+			log.Debug().Err(err).Int("postId", reply.Postid).Msg("Ignoring invalid command in reply")
+		}
+
+		err = s.publisher.PublishMatchComment(dotlanContext, &messagequeue.MatchComment{
+			MatchID:   dotlanForumState.ID,
+			ThreadID:  reply.Threadid,
+			PostID:    reply.Postid,
+			UserID:    reply.Userid,
+			Nick:      reply.Nick,
+			Text:      reply.Pagetext,
+			HTML:      reply.Htmltext,
+			CreatedAt: reply.Dateline,
+		})
+		if err != nil {
+			log.Error().Err(err).Int("postId", reply.Postid).Msg("Error publishing reply of forum thread")
+			return
+		}
+
+		if matchCommand != nil {
+			if err = s.publisher.PublishMatchCommand(dotlanContext, matchCommand); err != nil {
+				log.Error().Err(err).Int("postId", reply.Postid).Msg("Error publishing command of forum thread")
+				return
+			}
+		}
+
+		dotlanForumState.LastSeenPostID = reply.Postid
+		if err = s.dbClient.Upsert(context.TODO(), dotlanForumState); err != nil {
+			log.Error().Err(err).Int("postId", reply.Postid).Msg("Error storing last seen post of forum thread")
+			return
+		}
+	}
+
+	if len(replies) > 0 {
+		log.Info().Int("replies", len(replies)).Msg("Published replies of forum thread")
+	}
+}
+
+// teamsOfMatch reads the teams of the match from dotlan. Matches without dotlan contest have no teams, so all commands
+// are rejected.
+func (s *Server) teamsOfMatch(ctx context.Context, id string) (*dotlan.MatchData, error) {
+	matchData, err := s.dotlanClient.GetMatchData(ctx, id)
+	if errors.Is(err, dotlan.ErrContestNotFound) {
+		log.Warn().Str("matchId", id).Msg("No dotlan contest found for match, rejecting commands")
+		return &dotlan.MatchData{}, nil
+	}
+
+	return matchData, err
+}
+
+// commandOfTeamMember returns the command to publish for the reply, or nil if its author is member of none of the
+// teams of the match
+func commandOfTeamMember(matchId string, reply *dotlan.ForumReply, cmd *command.Command, matchData *dotlan.MatchData) *messagequeue.MatchCommand {
+	team, position := matchData.TeamOfUser(reply.Userid)
+	if team == nil {
+		log.Info().Str("matchId", matchId).Uint("userId", reply.Userid).Str("command", string(cmd.Name)).Msg("Rejecting command of user which is no member of the teams")
+		metrics.MatchCommands.WithLabelValues(string(cmd.Name), metrics.CommandRejected).Inc()
+		return nil
+	}
+
+	return &messagequeue.MatchCommand{
+		MatchID:   matchId,
+		PostID:    reply.Postid,
+		UserID:    reply.Userid,
+		Nick:      reply.Nick,
+		TeamID:    team.Tnid,
+		Team:      position,
+		Command:   string(cmd.Name),
+		Argument:  cmd.Argument,
+		CreatedAt: reply.Dateline,
+	}
+}
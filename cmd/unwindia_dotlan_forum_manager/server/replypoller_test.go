@@ -0,0 +1,52 @@
+package server
+
+import (
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/command"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/dotlan"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/messagequeue"
+	"reflect"
+	"testing"
+)
+
+func TestCommandOfTeamMember(t *testing.T) {
+	matchData := &dotlan.MatchData{
+		Team1: &dotlan.Team{Tnid: 11, Members: []dotlan.TeamMember{{UserId: 21, Nick: "alice"}}},
+		Team2: &dotlan.Team{Tnid: 12, Members: []dotlan.TeamMember{{UserId: 23, Nick: "carol"}}},
+	}
+
+	tests := []struct {
+		name  string
+		reply dotlan.ForumReply
+		cmd   command.Command
+		want  *messagequeue.MatchCommand
+	}{
+		{
+			name:  "ok-member_of_team2",
+			reply: dotlan.ForumReply{Postid: 5, Userid: 23, Nick: "carol"},
+			cmd:   command.Command{Name: command.Admin, Argument: "server down"},
+			want: &messagequeue.MatchCommand{
+				MatchID:  "1001",
+				PostID:   5,
+				UserID:   23,
+				Nick:     "carol",
+				TeamID:   12,
+				Team:     2,
+				Command:  "admin",
+				Argument: "server down",
+			},
+		},
+		{
+			name:  "ok-no_member",
+			reply: dotlan.ForumReply{Postid: 6, Userid: 31, Nick: "dave"},
+			cmd:   command.Command{Name: command.Ready},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := commandOfTeamMember("1001", &tt.reply, &tt.cmd, matchData)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commandOfTeamMember() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,37 +2,49 @@ package server
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"github.com/GSH-LAN/Unwindia_common/src/go/config"
 	"github.com/GSH-LAN/Unwindia_common/src/go/matchservice"
+	"github.com/GSH-LAN/Unwindia_common/src/go/messagebroker"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/config"
 	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/database"
 	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/dotlan"
 	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/environment"
 	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/messagequeue"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/metrics"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/router"
 	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/template"
 	"github.com/gammazero/workerpool"
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/mongo"
-	"sync"
+	"net/http"
 	"time"
 )
 
 type Server struct {
-	env           *environment.Environment
-	config        config.ConfigClient
-	workerpool    *workerpool.WorkerPool
-	subscriber    *messagequeue.Subscriber
-	matchInfoChan chan *matchservice.MatchInfo
-	lock          sync.Mutex
-	dotlanClient  dotlan.DotlanDbClient
-	dbClient      database.DatabaseClient
-	stop          chan struct{}
+	env            *environment.Environment
+	config         config.ConfigClient
+	workerpool     *workerpool.WorkerPool
+	subscriber     *messagequeue.Subscriber
+	publisher      *messagequeue.Publisher
+	matchEventChan chan *messagequeue.MatchEvent
+	handlers       map[messagebroker.MatchEvent][]forumAction
+	executor       *keyedExecutor
+	debouncer      *debouncer
+	dotlanClient   dotlan.DotlanDbClient
+	redaction      *template.RedactionPolicy
+	dbClient       database.DatabaseClient
+	httpServer     *http.Server
+	adminServer    *http.Server
+	stop           chan struct{}
 }
 
 func NewServer(ctx context.Context, env *environment.Environment, cfgClient config.ConfigClient, wp *workerpool.WorkerPool) (*Server, error) {
-	matchInfoChan := make(chan *matchservice.MatchInfo)
+	matchEventChan := make(chan *messagequeue.MatchEvent)
 
-	subscriber, err := messagequeue.NewSubscriber(ctx, env, matchInfoChan)
+	subscriber, err := messagequeue.NewSubscriber(ctx, env, matchEventChan)
 	if err != nil {
 		return nil, err
 	}
@@ -43,17 +55,64 @@ func NewServer(ctx context.Context, env *environment.Environment, cfgClient conf
 	}
 
 	dbClient, err := database.NewClient(ctx, env)
+	if err != nil {
+		return nil, err
+	}
+
+	redaction, err := credentialsPolicy(env)
+	if err != nil {
+		return nil, err
+	}
+
+	executor := newKeyedExecutor(wp)
+	metrics.RegisterWorkerpool(wp, executor.Backlog)
 
 	srv := Server{
-		env:           env,
-		config:        cfgClient,
-		workerpool:    wp,
-		subscriber:    subscriber,
-		matchInfoChan: matchInfoChan,
-		lock:          sync.Mutex{},
-		dotlanClient:  dotlanClient,
-		dbClient:      dbClient,
-		stop:          make(chan struct{}),
+		env:            env,
+		config:         cfgClient,
+		workerpool:     wp,
+		subscriber:     subscriber,
+		publisher:      subscriber.Publisher(),
+		matchEventChan: matchEventChan,
+		executor:       executor,
+		dotlanClient:   dotlanClient,
+		redaction:      redaction,
+		dbClient:       dbClient,
+		stop:           make(chan struct{}),
+	}
+
+	srv.debouncer = newDebouncer(env.MatchDebounceWindow, env.MatchDebounceMaxDelay, func(key string, events []*messagequeue.MatchEvent) {
+		srv.executor.Submit(key, func() {
+			srv.matchEventsHandler(events)
+		})
+	})
+
+	srv.registerHandlers()
+
+	srv.httpServer = &http.Server{
+		Addr: fmt.Sprintf(":%d", env.HTTPPort),
+		Handler: router.NewRouter(map[string]router.HealthCheck{
+			"mysql":   dotlanClient.Ping,
+			"mongodb": dbClient.Ping,
+			"pulsar":  subscriber.Ping,
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	if env.AdminToken != "" {
+		// the admin api returns stored MatchInfos and dead letters, so credentials are always masked
+		adminRedaction, err := template.NewRedactionPolicy(append(append([]string{}, env.TemplateSensitiveFields...), template.CredentialFields...), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		srv.adminServer = &http.Server{
+			Addr:              fmt.Sprintf(":%d", env.AdminHTTPPort),
+			Handler:           router.NewAdminRouter(dbClient, &srv, &srv, subscriber.DeadLetterQueue(), adminRedaction, env.AdminToken),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+	} else {
+		log.Warn().Msg("No ADMIN_TOKEN configured, the admin api is disabled")
 	}
 
 	return &srv, nil
@@ -61,15 +120,33 @@ func NewServer(ctx context.Context, env *environment.Environment, cfgClient conf
 
 func (s *Server) Start() error {
 	s.subscriber.StartConsumer()
+	s.startReconciler()
+	s.startReplyPoller()
+	s.startContestSync()
+
+	go func() {
+		log.Info().Str("address", s.httpServer.Addr).Msg("Starting http server")
+		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Msg("Error running http server")
+		}
+	}()
+
+	if s.adminServer != nil {
+		go func() {
+			log.Info().Str("address", s.adminServer.Addr).Msg("Starting admin http server")
+			if err := s.adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error().Err(err).Msg("Error running admin http server")
+			}
+		}()
+	}
+
 	for {
 		select {
 		case <-s.stop:
 			log.Info().Msg("Stopping processing, server stopped")
 			return nil
-		case matchInfo := <-s.matchInfoChan:
-			s.workerpool.Submit(func() {
-				s.matchInfoHandler(matchInfo)
-			})
+		case matchEvent := <-s.matchEventChan:
+			s.debouncer.Push(matchEvent.MatchInfo.MsID, matchEvent)
 		}
 	}
 }
@@ -77,63 +154,402 @@ func (s *Server) Start() error {
 func (s *Server) Stop() error {
 	log.Info().Msgf("Stopping server")
 	close(s.stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		log.Error().Err(err).Msg("Error stopping http server")
+	}
+	if s.adminServer != nil {
+		if err := s.adminServer.Shutdown(ctx); err != nil {
+			log.Error().Err(err).Msg("Error stopping admin http server")
+		}
+	}
+
 	return fmt.Errorf("server Stopped")
 }
 
-func (s *Server) matchInfoHandler(matchInfo *matchservice.MatchInfo) {
-	log := log.With().Str("matchId", matchInfo.MsID).Logger()
+// RenderMatch renders and writes the forum post for an already known match again, using the stored MatchInfo. The post
+// is written even if the rendered text is unchanged.
+func (s *Server) RenderMatch(ctx context.Context, id string) error {
+	dotlanForumState, err := s.dbClient.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if dotlanForumState.MatchInfo == nil {
+		return database.ErrNoMatchInfo
+	}
+
+	subType, ok := messagebroker.EventsValue[dotlanForumState.LastEvent]
+	if !ok {
+		return fmt.Errorf("%w: %q", database.ErrUnknownLastEvent, dotlanForumState.LastEvent)
+	}
+
+	event := messagequeue.MatchEvent{
+		SubType:   subType,
+		MatchInfo: dotlanForumState.MatchInfo,
+	}
+
+	result := make(chan error, 1)
+	s.executor.Submit(id, func() {
+		result <- s.handleEvent(&event, s.resetContentHash, s.updateForumPost)
+	})
+
+	select {
+	case err = <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
-	log.Debug().Interface("matchInfo", matchInfo).Msg("Received match info")
+// resetContentHash clears the stored content hash of the match, so the next update of the forum post is not skipped
+func (s *Server) resetContentHash(event *messagequeue.MatchEvent) error {
+	dotlanForumState, err := s.dbClient.Get(context.TODO(), event.MatchInfo.MsID)
+	if err != nil {
+		return err
+	}
+
+	dotlanForumState.ContentHash = ""
+	return s.dbClient.Upsert(context.TODO(), dotlanForumState)
+}
+
+// gameForMatch returns the game of the match, or the configured default game if the match has none
+func (s *Server) gameForMatch(matchInfo *matchservice.MatchInfo) string {
+	if matchInfo.Game != "" {
+		return matchInfo.Game
+	}
+	return s.config.GetConfig().CmsConfig.DefaultGame
+}
+
+// handleEvent runs the given handlers for the event and records a failure as last error of the match
+func (s *Server) handleEvent(event *messagequeue.MatchEvent, handlers ...eventHandler) error {
+	log := log.With().Str("matchId", event.MatchInfo.MsID).Str("subType", event.SubType.String()).Logger()
+
+	log.Debug().Interface("matchInfo", s.redaction.Redact(event.MatchInfo)).Msg("Received match info")
+
+	start := time.Now()
+	result := metrics.ResultSuccess
+
+	var err error
+	for _, handler := range handlers {
+		if err = handler(event); err != nil {
+			break
+		}
+	}
+
+	if err != nil {
+		result = metrics.ResultError
+		log.Error().Err(err).Msg("Error processing match event")
+		s.recordError(event.MatchInfo, err)
+	}
 
-	s.lock.Lock()
-	defer s.lock.Unlock()
+	metrics.MatchProcessingDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
 
-	commentText, err := template.ParseTemplateForMatch(s.config.GetConfig().Templates["CMS_FORUM_POST.gohtml"], matchInfo)
+	return err
+}
+
+// updateForumPost renders the forum post for the match and creates or updates it within dotlan
+func (s *Server) updateForumPost(event *messagequeue.MatchEvent) error {
+	matchInfo := event.MatchInfo
+	log := log.With().Str("matchId", matchInfo.MsID).Logger()
+
+	cfg := s.config.GetConfig()
+	templateName, tpl, err := template.SelectTemplate(cfg.Templates, template.ForumPostTemplate, s.gameForMatch(matchInfo), event.SubType.String())
 	if err != nil {
-		log.Error().Err(err).Msg("Error parsing template")
+		metrics.TemplateRenderFailures.Inc()
+		return fmt.Errorf("error selecting template: %w", err)
 	}
-	log.Debug().Str("commentText", commentText).Msg("parsed Template")
+	log.Debug().Str("template", templateName).Msg("selected Template")
 
 	dotlanForumState, err := s.dbClient.Get(context.TODO(), matchInfo.MsID)
 	if err != nil && err != mongo.ErrNoDocuments {
-		log.Error().Err(err).Msg("Failed to get dotlan forum state")
+		return fmt.Errorf("failed to get dotlan forum state: %w", err)
+	}
+
+	forumId, err := s.forumIdForMatch(matchInfo, dotlanForumState)
+	if err != nil {
+		return err
+	}
+
+	matchContext, err := s.matchContext(matchInfo, forumId, dotlanForumState)
+	if err != nil {
+		return err
+	}
+
+	commentText, err := template.ParseTemplate(tpl, matchContext)
+	if err != nil {
+		metrics.TemplateRenderFailures.Inc()
+		return fmt.Errorf("error parsing template: %w", err)
+	}
+	log.Debug().Str("commentText", s.redaction.Scrub(matchInfo, commentText)).Msg("parsed Template")
+	hash := contentHash(commentText)
+
+	title, err := s.renderThreadTitle(event, matchContext)
+	if err != nil {
+		return err
 	}
 
 	dotlanContext, cancel := context.WithTimeout(context.TODO(), time.Second*30)
 	defer cancel()
 
 	if dotlanForumState == nil {
+		dotlanForumState = &database.DotlanForumStatus{
+			ID:        matchInfo.MsID,
+			CreatedAt: time.Now(),
+		}
+	}
 
-		threadId, postId, err := s.dotlanClient.UpsertForumPostForMatch(dotlanContext, matchInfo, commentText)
-		if err != nil {
-			log.Error().Err(err).Msg("Error upserting forum post for match")
-			return
+	if dotlanForumState.HasForumPost() && dotlanForumState.ThreadTitle != title {
+		err = s.dotlanClient.UpdateForumThreadTitle(dotlanContext, dotlanForumState.DotlanForumThreadID, title)
+		if errors.Is(err, dotlan.ErrForumThreadNotFound) {
+			s.recordDrift(dotlanForumState, metrics.DriftThreadMissing)
+			dotlanForumState.DotlanForumThreadID = 0
+			dotlanForumState.DotlanForumPostID = 0
+			dotlanForumState.DotlanResultPostID = 0
+			dotlanForumState.DotlanForumID = 0
+			dotlanForumState.ThreadClosed = false
+			dotlanForumState.ThreadArchived = false
+			dotlanForumState.ContentHash = ""
+
+			// the new thread may be located in another forum, so the post has to be rendered again for that forum
+			if err = s.dbClient.Upsert(context.TODO(), dotlanForumState); err != nil {
+				return fmt.Errorf("error upserting dotlanForumState: %w", err)
+			}
+			return s.updateForumPost(event)
+		} else if err != nil {
+			return fmt.Errorf("error updating forum thread title for match: %w", err)
+		} else {
+			dotlanForumState.ThreadTitle = title
 		}
+	}
 
-		dotlanForumState = &database.DotlanForumStatus{
-			ID:                  matchInfo.MsID,
-			DotlanForumPostID:   postId,
-			DotlanForumThreadID: threadId,
-			CreatedAt:           time.Now(),
-			UpdatedAt:           time.Time{},
+	if dotlanForumState.HasForumPost() {
+		if dotlanForumState.ContentHash == hash {
+			log.Debug().Msg("Rendered forum post is unchanged, skipping update")
+			metrics.ForumWritesSkipped.Inc()
+		} else {
+			log.Debug().Int("threadId", dotlanForumState.DotlanForumThreadID).Int("postId", dotlanForumState.DotlanForumPostID).Msg("Found dotlan forum state")
+
+			err = s.dotlanClient.UpdateForumPostForMatch(dotlanContext, dotlanForumState.DotlanForumPostID, postText(commentText))
+			if errors.Is(err, dotlan.ErrForumPostNotFound) {
+				s.recordDrift(dotlanForumState, metrics.DriftPostMissing)
+				dotlanForumState.DotlanForumPostID = 0
+			} else if err != nil {
+				return fmt.Errorf("error updating forum post for match: %w", err)
+			} else {
+				dotlanForumState.UpdatedAt = time.Now()
+			}
 		}
+	}
 
-		err = s.dbClient.Upsert(context.TODO(), dotlanForumState)
+	if !dotlanForumState.HasForumPost() {
+		threadId, postId, err := s.dotlanClient.CreateForumPostForMatch(dotlanContext, matchInfo, title, postText(commentText), dotlanForumState.DotlanResultPostID)
 		if err != nil {
-			log.Error().Err(err).Msg("Error upserting dotlanForumState")
-			return
+			return fmt.Errorf("error creating forum post for match: %w", err)
 		}
-	} else {
-		log.Debug().Interface("dotlanForumState", dotlanForumState).Msg("Found dotlan forum state")
 
-		s.dotlanClient.UpdateForumPostForMatch(dotlanContext, dotlanForumState.DotlanForumPostID, commentText)
+		dotlanForumState.DotlanForumPostID = postId
+		dotlanForumState.DotlanForumThreadID = threadId
+		dotlanForumState.ThreadTitle = title
+	}
+
+	dotlanForumState.ContentHash = hash
+	dotlanForumState.DotlanForumID = forumId
+
+	dotlanForumState.MatchInfo = matchInfo
+	dotlanForumState.LastEvent = event.SubType.String()
+	dotlanForumState.LastError = ""
+	dotlanForumState.LastErrorAt = time.Time{}
+
+	err = s.dbClient.Upsert(context.TODO(), dotlanForumState)
+	if err != nil {
+		return fmt.Errorf("error upserting dotlanForumState: %w", err)
+	}
+
+	return nil
+}
+
+// matchContext returns the template data for the match, enriched with the contest and team records of dotlan. Matches
+// which are unknown to dotlan are rendered without dotlan records. Sensitive fields of the MatchInfo are masked unless
+// the thread of the match is located in a private forum.
+func (s *Server) matchContext(matchInfo *matchservice.MatchInfo, forumId int, dotlanForumState *database.DotlanForumStatus) (*template.MatchContext, error) {
+	matchContext := template.MatchContext{
+		MatchInfo:           s.redaction.MatchInfoForForum(matchInfo, forumId),
+		CredentialsSentByPM: dotlanForumState != nil && dotlanForumState.CredentialsHash != "",
+	}
+
+	dotlanContext, cancel := context.WithTimeout(context.TODO(), time.Second*30)
+	defer cancel()
 
-		dotlanForumState.UpdatedAt = time.Now()
+	matchData, err := s.dotlanClient.GetMatchData(dotlanContext, matchInfo.MsID)
+	if errors.Is(err, dotlan.ErrContestNotFound) {
+		log.Warn().Str("matchId", matchInfo.MsID).Msg("Contest of match not found within dotlan, rendering without dotlan records")
+		return &matchContext, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading dotlan records of match: %w", err)
+	}
 
-		err = s.dbClient.Upsert(context.TODO(), dotlanForumState)
+	matchContext.Dotlan = matchData
+	return &matchContext, nil
+}
+
+// forumIdForMatch returns the forum the thread of the match is located in: the forum stored when the thread was
+// created or archived, else the forum of an existing thread within dotlan. Only matches without thread are routed to
+// the forum a new thread is created in, so a changed routing never affects existing threads. The state may be nil for
+// new matches.
+func (s *Server) forumIdForMatch(matchInfo *matchservice.MatchInfo, dotlanForumState *database.DotlanForumStatus) (int, error) {
+	if dotlanForumState != nil && dotlanForumState.DotlanForumID > 0 {
+		return dotlanForumState.DotlanForumID, nil
+	}
+
+	dotlanContext, cancel := context.WithTimeout(context.TODO(), time.Second*30)
+	defer cancel()
+
+	forumId, err := s.dotlanClient.GetThreadForumId(dotlanContext, matchInfo.MsID)
+	if errors.Is(err, dotlan.ErrForumThreadNotFound) {
+		return s.dotlanClient.ForumIdForMatch(matchInfo), nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error reading forum of match thread: %w", err)
+	}
+
+	return forumId, nil
+}
+
+// renderThreadTitle renders the forum thread title for the match. The MatchTitle is used as title if no title template
+// is configured.
+func (s *Server) renderThreadTitle(event *messagequeue.MatchEvent, matchContext *template.MatchContext) (string, error) {
+	matchInfo := event.MatchInfo
+
+	cfg := s.config.GetConfig()
+	templateName, tpl, err := template.SelectTemplate(cfg.Templates, template.ForumThreadTitleTemplate, s.gameForMatch(matchInfo), event.SubType.String())
+	if errors.Is(err, template.ErrTemplateNotFound) {
+		return matchInfo.MatchTitle, nil
+	}
+	if err != nil {
+		metrics.TemplateRenderFailures.Inc()
+		return "", fmt.Errorf("error selecting title template: %w", err)
+	}
+	log.Debug().Str("matchId", matchInfo.MsID).Str("template", templateName).Msg("selected title Template")
+
+	title, err := template.ParseTitle(tpl, matchContext)
+	if err != nil {
+		metrics.TemplateRenderFailures.Inc()
+		return "", fmt.Errorf("error parsing title template: %w", err)
+	}
+
+	return title, nil
+}
+
+// closeForumThread posts the result of the finished match into its forum thread and closes the thread. Threads which
+// are already closed are skipped.
+func (s *Server) closeForumThread(event *messagequeue.MatchEvent) error {
+	matchInfo := event.MatchInfo
+	log := log.With().Str("matchId", matchInfo.MsID).Logger()
+
+	dotlanForumState, err := s.dbClient.Get(context.TODO(), matchInfo.MsID)
+	if err != nil {
+		return fmt.Errorf("failed to get dotlan forum state: %w", err)
+	}
+
+	if !dotlanForumState.HasForumPost() {
+		return fmt.Errorf("no forum thread to close for match")
+	}
+
+	if dotlanForumState.ThreadClosed {
+		log.Debug().Msg("Forum thread is already closed, skipping")
+		metrics.ForumOperationsSkipped.WithLabelValues(metrics.OperationThreadClosed).Inc()
+		return nil
+	}
+
+	var resultText dotlan.PostText
+	cfg := s.config.GetConfig()
+	templateName, tpl, err := template.SelectTemplate(cfg.Templates, template.ForumResultTemplate, s.gameForMatch(matchInfo), event.SubType.String())
+	switch {
+	case errors.Is(err, template.ErrTemplateNotFound):
+		log.Debug().Msg("No result template configured, closing forum thread without result post")
+	case err != nil:
+		metrics.TemplateRenderFailures.Inc()
+		return fmt.Errorf("error selecting result template: %w", err)
+	default:
+		log.Debug().Str("template", templateName).Msg("selected result Template")
+
+		forumId, err := s.forumIdForMatch(matchInfo, dotlanForumState)
+		if err != nil {
+			return err
+		}
+
+		matchContext, err := s.matchContext(matchInfo, forumId, dotlanForumState)
 		if err != nil {
-			log.Error().Err(err).Msg("Error upserting dotlanForumState")
+			return err
+		}
+
+		resultHtml, err := template.ParseTemplate(tpl, matchContext)
+		if err != nil {
+			metrics.TemplateRenderFailures.Inc()
+			return fmt.Errorf("error parsing result template: %w", err)
+		}
+		resultText = postText(resultHtml)
+	}
+
+	dotlanContext, cancel := context.WithTimeout(context.TODO(), time.Second*30)
+	defer cancel()
+
+	resultPostId, err := s.dotlanClient.CloseForumThreadForMatch(dotlanContext, matchInfo, dotlanForumState.DotlanForumThreadID, dotlanForumState.DotlanForumPostID, resultText, s.env.DotlanLockPostOnFinish)
+	if err != nil {
+		return fmt.Errorf("error closing forum thread for match: %w", err)
+	}
+
+	dotlanForumState.DotlanResultPostID = resultPostId
+	dotlanForumState.ThreadClosed = true
+	dotlanForumState.UpdatedAt = time.Now()
+
+	err = s.dbClient.Upsert(context.TODO(), dotlanForumState)
+	if err != nil {
+		return fmt.Errorf("error upserting dotlanForumState: %w", err)
+	}
+
+	return nil
+}
+
+// recordError stores the given error as last error of the match, so it can be inspected through the admin api
+func (s *Server) recordError(matchInfo *matchservice.MatchInfo, processErr error) {
+	dotlanForumState, err := s.dbClient.Get(context.TODO(), matchInfo.MsID)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Error().Err(err).Str("matchId", matchInfo.MsID).Msg("Failed to get dotlan forum state for recording error")
 			return
 		}
+
+		dotlanForumState = &database.DotlanForumStatus{
+			ID:        matchInfo.MsID,
+			CreatedAt: time.Now(),
+		}
+	}
+
+	dotlanForumState.MatchInfo = matchInfo
+	dotlanForumState.LastError = processErr.Error()
+	dotlanForumState.LastErrorAt = time.Now()
+
+	if err = s.dbClient.Upsert(context.TODO(), dotlanForumState); err != nil {
+		log.Error().Err(err).Str("matchId", matchInfo.MsID).Msg("Error recording last error in dotlanForumState")
 	}
 }
+
+// postText returns the rendered html of a post together with its BBCode source, which the dotlan editor loads when the
+// post is edited within dotlan
+func postText(html string) dotlan.PostText {
+	return dotlan.PostText{
+		Pagetext: template.HTMLToBBCode(html),
+		Htmltext: html,
+	}
+}
+
+// contentHash returns the hex encoded sha256 hash of the rendered text
+func contentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
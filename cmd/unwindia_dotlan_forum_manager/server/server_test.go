@@ -0,0 +1,202 @@
+package server
+
+import (
+	"context"
+	"errors"
+	unwindiaConfig "github.com/GSH-LAN/Unwindia_common/src/go/config"
+	"github.com/GSH-LAN/Unwindia_common/src/go/matchservice"
+	"github.com/GSH-LAN/Unwindia_common/src/go/messagebroker"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/config"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/database"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/dotlan"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/messagequeue"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/metrics"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/template"
+	"github.com/gammazero/workerpool"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"reflect"
+	"testing"
+)
+
+type testConfigClient struct {
+	config *config.Config
+}
+
+func (t testConfigClient) GetConfig() *config.Config {
+	return t.config
+}
+
+// testForumDotlanClient records the written forum posts of a match which is unknown to dotlan, all other methods are
+// not implemented
+type testForumDotlanClient struct {
+	dotlan.DotlanDbClient
+	updatedPosts []dotlan.PostText
+	createdPosts []dotlan.PostText
+}
+
+func (t *testForumDotlanClient) GetMatchData(_ context.Context, _ string) (*dotlan.MatchData, error) {
+	return nil, dotlan.ErrContestNotFound
+}
+
+func (t *testForumDotlanClient) UpdateForumPostForMatch(_ context.Context, _ int, text dotlan.PostText) error {
+	t.updatedPosts = append(t.updatedPosts, text)
+	return nil
+}
+
+func (t *testForumDotlanClient) CreateForumPostForMatch(_ context.Context, _ *matchservice.MatchInfo, _ string, text dotlan.PostText, _ int) (int, int, error) {
+	t.createdPosts = append(t.createdPosts, text)
+	return 1, 1, nil
+}
+
+// testForumDatabaseClient stores the state of a single match in memory, all other methods are not implemented
+type testForumDatabaseClient struct {
+	database.DatabaseClient
+	state     *database.DotlanForumStatus
+	upsertErr error
+}
+
+func (t *testForumDatabaseClient) Get(_ context.Context, _ string) (*database.DotlanForumStatus, error) {
+	state := *t.state
+	return &state, nil
+}
+
+func (t *testForumDatabaseClient) Upsert(_ context.Context, state *database.DotlanForumStatus) error {
+	if t.upsertErr != nil {
+		return t.upsertErr
+	}
+	stored := *state
+	t.state = &stored
+	return nil
+}
+
+func TestServer_updateForumPost(t *testing.T) {
+	const tpl = "<p>{{.MatchTitle}}</p>"
+
+	matchInfo := &matchservice.MatchInfo{MsID: "1001", MatchTitle: "cool-team vs nice-team"}
+	rendered, err := template.ParseTemplate(tpl, &template.MatchContext{MatchInfo: matchInfo})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name             string
+		contentHash      string
+		wantUpdatedPosts []dotlan.PostText
+		wantSkipped      float64
+	}{
+		{
+			name:        "ok-unchanged_skipped",
+			contentHash: contentHash(rendered),
+			wantSkipped: 1,
+		},
+		{
+			name:             "ok-changed_written",
+			contentHash:      contentHash("<p>outdated</p>"),
+			wantUpdatedPosts: []dotlan.PostText{postText(rendered)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dotlanClient := &testForumDotlanClient{}
+			dbClient := &testForumDatabaseClient{state: &database.DotlanForumStatus{
+				ID:                  matchInfo.MsID,
+				DotlanForumThreadID: 11,
+				DotlanForumPostID:   12,
+				DotlanForumID:       9,
+				ThreadTitle:         matchInfo.MatchTitle,
+				ContentHash:         tt.contentHash,
+			}}
+			s := &Server{
+				config: testConfigClient{config: &config.Config{
+					Config: unwindiaConfig.Config{
+						Templates: map[string]string{template.ForumPostTemplate + ".gohtml": tpl},
+					},
+				}},
+				dotlanClient: dotlanClient,
+				dbClient:     dbClient,
+			}
+
+			skipped := testutil.ToFloat64(metrics.ForumWritesSkipped)
+			err := s.updateForumPost(&messagequeue.MatchEvent{SubType: messagebroker.UNWINDIA_MATCH_NEW, MatchInfo: matchInfo})
+			if err != nil {
+				t.Fatalf("updateForumPost() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(dotlanClient.updatedPosts, tt.wantUpdatedPosts) {
+				t.Errorf("updateForumPost() updated posts = %v, want %v", dotlanClient.updatedPosts, tt.wantUpdatedPosts)
+			}
+			if len(dotlanClient.createdPosts) > 0 {
+				t.Errorf("updateForumPost() created posts = %v, want none", dotlanClient.createdPosts)
+			}
+			if got := testutil.ToFloat64(metrics.ForumWritesSkipped) - skipped; got != tt.wantSkipped {
+				t.Errorf("updateForumPost() skipped writes = %v, want %v", got, tt.wantSkipped)
+			}
+			if dbClient.state.ContentHash != contentHash(rendered) {
+				t.Errorf("updateForumPost() content hash = %v, want %v", dbClient.state.ContentHash, contentHash(rendered))
+			}
+		})
+	}
+}
+
+func TestServer_RenderMatch(t *testing.T) {
+	wp := workerpool.New(1)
+	defer wp.StopWait()
+
+	tests := []struct {
+		name      string
+		lastEvent string
+		wantErr   error
+	}{
+		{
+			name:      "ok-known_last_event",
+			lastEvent: "UNWINDIA_MATCH_READY_ALL",
+		},
+		{
+			name:    "err-no_last_event",
+			wantErr: database.ErrUnknownLastEvent,
+		},
+		{
+			name:      "err-unknown_last_event",
+			lastEvent: "UNWINDIA_MATCH_SOMETHING",
+			wantErr:   database.ErrUnknownLastEvent,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matchInfo := &matchservice.MatchInfo{MsID: "1001", MatchTitle: "cool-team vs nice-team"}
+			dotlanClient := &testForumDotlanClient{}
+			s := &Server{
+				config: testConfigClient{config: &config.Config{
+					Config: unwindiaConfig.Config{
+						Templates: map[string]string{template.ForumPostTemplate + ".gohtml": "<p>{{.MatchTitle}}</p>"},
+					},
+				}},
+				executor:     newKeyedExecutor(wp),
+				dotlanClient: dotlanClient,
+				dbClient: &testForumDatabaseClient{state: &database.DotlanForumStatus{
+					ID:                  matchInfo.MsID,
+					DotlanForumThreadID: 11,
+					DotlanForumPostID:   12,
+					DotlanForumID:       9,
+					ThreadTitle:         matchInfo.MatchTitle,
+					MatchInfo:           matchInfo,
+					LastEvent:           tt.lastEvent,
+				}},
+			}
+
+			err := s.RenderMatch(context.Background(), matchInfo.MsID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RenderMatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			// an unknown subtype must never be rendered with the template of another subtype
+			wantUpdates := 0
+			if tt.wantErr == nil {
+				wantUpdates = 1
+			}
+			if len(dotlanClient.updatedPosts) != wantUpdates {
+				t.Errorf("RenderMatch() updated posts = %v, want %v", len(dotlanClient.updatedPosts), wantUpdates)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package template
+
+import (
+	"golang.org/x/net/html"
+	"strings"
+)
+
+// bbCodeTags maps html tags to the BBCode tags of the dotlan editor which have the same meaning
+var bbCodeTags = map[string]string{
+	"b":          "b",
+	"strong":     "b",
+	"i":          "i",
+	"em":         "i",
+	"u":          "u",
+	"s":          "s",
+	"strike":     "s",
+	"del":        "s",
+	"code":       "code",
+	"pre":        "code",
+	"blockquote": "quote",
+	"center":     "center",
+	"h1":         "b",
+	"h2":         "b",
+	"h3":         "b",
+	"h4":         "b",
+	"h5":         "b",
+	"h6":         "b",
+}
+
+// HTMLToBBCode converts the rendered html of a forum post into the BBCode source which is loaded by the dotlan editor.
+// Line breaks of the text are kept, since the rendered posts use them as line breaks. Tags without BBCode counterpart
+// are dropped while their text is kept, html entities are unescaped.
+func HTMLToBBCode(text string) string {
+	var builder strings.Builder
+	// open links, so closing anchors without href don't write a closing url tag
+	var links []bool
+
+	tokenizer := html.NewTokenizer(strings.NewReader(text))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			// the tokenizer returns io.EOF at the end of the text
+			return builder.String()
+		}
+
+		token := tokenizer.Token()
+		switch tokenType {
+		case html.TextToken:
+			builder.WriteString(token.Data)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			switch token.Data {
+			case "br":
+				builder.WriteString("\n")
+			case "img":
+				if src := attribute(token, "src"); src != "" {
+					builder.WriteString("[img]" + src + "[/img]")
+				}
+			case "a":
+				href := attribute(token, "href")
+				links = append(links, href != "")
+				if href != "" {
+					builder.WriteString("[url=" + href + "]")
+				}
+			case "ul":
+				builder.WriteString("[list]")
+			case "ol":
+				builder.WriteString("[list=1]")
+			case "li":
+				builder.WriteString("[*]")
+			default:
+				if tag, ok := bbCodeTags[token.Data]; ok {
+					builder.WriteString("[" + tag + "]")
+				}
+			}
+		case html.EndTagToken:
+			switch token.Data {
+			case "a":
+				if len(links) > 0 {
+					if links[len(links)-1] {
+						builder.WriteString("[/url]")
+					}
+					links = links[:len(links)-1]
+				}
+			case "ul", "ol":
+				builder.WriteString("[/list]")
+			case "p", "div":
+				builder.WriteString("\n")
+			default:
+				if tag, ok := bbCodeTags[token.Data]; ok {
+					builder.WriteString("[/" + tag + "]")
+				}
+			}
+		}
+	}
+}
+
+func attribute(token html.Token, key string) string {
+	for _, attr := range token.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,101 @@
+package template
+
+import (
+	"fmt"
+	"github.com/GSH-LAN/Unwindia_common/src/go/matchservice"
+	"reflect"
+	"strings"
+)
+
+// RedactedValue replaces the values of sensitive fields in the templates of public forums and in logs
+const RedactedValue = "********"
+
+// DefaultSensitiveFields are the MatchInfo fields which are only passed to the templates of private forums by default
+var DefaultSensitiveFields = []string{"ServerPasswordMgmt"}
+
+// CredentialFields are the MatchInfo fields which are sent as private message instead of being posted into the forum
+var CredentialFields = []string{"ServerPassword", "ServerPasswordMgmt", "ServerTvPassword"}
+
+// RedactionPolicy marks MatchInfo fields as sensitive. The templates of forums configured as private receive the
+// sensitive values, all other forums receive RedactedValue instead.
+type RedactionPolicy struct {
+	sensitiveFields []string
+	privateForums   map[int]bool
+}
+
+// NewRedactionPolicy creates the policy for the given MatchInfo fields and private forums. Every field has to be a
+// string field of MatchInfo, e.g. ServerPassword.
+func NewRedactionPolicy(sensitiveFields []string, privateForumIds []int) (*RedactionPolicy, error) {
+	matchInfoType := reflect.TypeOf(matchservice.MatchInfo{})
+
+	policy := RedactionPolicy{
+		privateForums: make(map[int]bool, len(privateForumIds)),
+	}
+
+	for _, name := range sensitiveFields {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		field, ok := matchInfoType.FieldByName(name)
+		if !ok || field.Type.Kind() != reflect.String {
+			return nil, fmt.Errorf("sensitive field %s is no string field of MatchInfo", name)
+		}
+		policy.sensitiveFields = append(policy.sensitiveFields, name)
+	}
+
+	for _, forumId := range privateForumIds {
+		policy.privateForums[forumId] = true
+	}
+
+	return &policy, nil
+}
+
+// IsPrivate returns true if the forum is configured as private
+func (p *RedactionPolicy) IsPrivate(forumId int) bool {
+	return p != nil && p.privateForums[forumId]
+}
+
+// MatchInfoForForum returns the MatchInfo to pass to the templates of the given forum. Private forums get the MatchInfo
+// unchanged, all others a redacted copy.
+func (p *RedactionPolicy) MatchInfoForForum(matchInfo *matchservice.MatchInfo, forumId int) *matchservice.MatchInfo {
+	if p.IsPrivate(forumId) {
+		return matchInfo
+	}
+	return p.Redact(matchInfo)
+}
+
+// Redact returns a copy of the MatchInfo with all non-empty sensitive fields set to RedactedValue
+func (p *RedactionPolicy) Redact(matchInfo *matchservice.MatchInfo) *matchservice.MatchInfo {
+	if p == nil || matchInfo == nil || len(p.sensitiveFields) == 0 {
+		return matchInfo
+	}
+
+	redacted := *matchInfo
+	value := reflect.ValueOf(&redacted).Elem()
+	for _, name := range p.sensitiveFields {
+		if field := value.FieldByName(name); field.String() != "" {
+			field.SetString(RedactedValue)
+		}
+	}
+
+	return &redacted
+}
+
+// Scrub replaces the values of the sensitive fields of the MatchInfo within the text by RedactedValue, so rendered
+// texts can be logged
+func (p *RedactionPolicy) Scrub(matchInfo *matchservice.MatchInfo, text string) string {
+	if p == nil || matchInfo == nil {
+		return text
+	}
+
+	value := reflect.ValueOf(matchInfo).Elem()
+	for _, name := range p.sensitiveFields {
+		if secret := value.FieldByName(name).String(); secret != "" {
+			text = strings.ReplaceAll(text, secret, RedactedValue)
+		}
+	}
+
+	return text
+}
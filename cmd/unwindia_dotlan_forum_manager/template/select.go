@@ -0,0 +1,65 @@
+package template
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	// ForumPostTemplate is the base name of the templates used for the forum post of a match
+	ForumPostTemplate = "CMS_FORUM_POST"
+	// ForumResultTemplate is the base name of the templates used for the result post of a finished match
+	ForumResultTemplate = "CMS_FORUM_RESULT"
+	// ForumThreadTitleTemplate is the base name of the templates used for the forum thread title of a match
+	ForumThreadTitleTemplate = "CMS_FORUM_THREAD_TITLE"
+	// CredentialsMessageTemplate is the base name of the templates used for the private message with the server
+	// credentials of a match
+	CredentialsMessageTemplate = "CMS_PM_CREDENTIALS"
+	// CredentialsSubjectTemplate is the base name of the templates used for the subject of the credentials message
+	CredentialsSubjectTemplate = "CMS_PM_CREDENTIALS_SUBJECT"
+
+	templateExtension = ".gohtml"
+	subTypePrefix     = "UNWINDIA_"
+)
+
+var (
+	// ErrTemplateNotFound is returned if none of the templates of a fallback chain exists
+	ErrTemplateNotFound = errors.New("template not found")
+)
+
+// TemplateNames returns the fallback chain of template names for the given base name, game and event subtype, ordered
+// from the most specific to the most generic name. The subtype is used without its UNWINDIA_ prefix, e.g. for game csgo
+// and subtype UNWINDIA_MATCH_FINISHED the chain is:
+//
+//	CMS_FORUM_POST.csgo.MATCH_FINISHED.gohtml
+//	CMS_FORUM_POST.MATCH_FINISHED.gohtml
+//	CMS_FORUM_POST.csgo.gohtml
+//	CMS_FORUM_POST.gohtml
+func TemplateNames(base, game, subType string) []string {
+	subType = strings.TrimPrefix(subType, subTypePrefix)
+
+	var names []string
+	if game != "" && subType != "" {
+		names = append(names, base+"."+game+"."+subType+templateExtension)
+	}
+	if subType != "" {
+		names = append(names, base+"."+subType+templateExtension)
+	}
+	if game != "" {
+		names = append(names, base+"."+game+templateExtension)
+	}
+
+	return append(names, base+templateExtension)
+}
+
+// SelectTemplate returns the name and content of the most specific template of the fallback chain that exists within
+// the given templates
+func SelectTemplate(templates map[string]string, base, game, subType string) (string, string, error) {
+	for _, name := range TemplateNames(base, game, subType) {
+		if tpl, ok := templates[name]; ok {
+			return name, tpl, nil
+		}
+	}
+
+	return "", "", ErrTemplateNotFound
+}
@@ -3,13 +3,29 @@ package template
 import (
 	"errors"
 	"github.com/GSH-LAN/Unwindia_common/src/go/matchservice"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/dotlan"
 	"github.com/rs/zerolog/log"
 	"strings"
 	"text/template"
 )
 
+// MatchContext is the data passed to the templates. The fields of the MatchInfo are accessible directly, e.g.
+// .Team1.Name, while .Dotlan holds the records read from dotlan for the match, e.g. .Dotlan.Contest.Round or
+// .Dotlan.Team1.Members. Dotlan is nil if the match is unknown to dotlan. CredentialsSentByPM is true once the server
+// credentials were sent to the team members as private messages.
+type MatchContext struct {
+	*matchservice.MatchInfo
+	Dotlan              *dotlan.MatchData
+	CredentialsSentByPM bool
+}
+
 func ParseTemplateForMatch(tpl string, matchinfo *matchservice.MatchInfo) (string, error) {
-	if matchinfo == nil {
+	return ParseTemplate(tpl, &MatchContext{MatchInfo: matchinfo})
+}
+
+// ParseTemplate renders the template for the match context
+func ParseTemplate(tpl string, data *MatchContext) (string, error) {
+	if data == nil || data.MatchInfo == nil {
 		return "", errors.New("empty matchinfo")
 	}
 
@@ -20,7 +36,7 @@ func ParseTemplateForMatch(tpl string, matchinfo *matchservice.MatchInfo) (strin
 	}
 
 	parsedTemplate := strings.Builder{}
-	err = tmpl.Execute(&parsedTemplate, matchinfo)
+	err = tmpl.Execute(&parsedTemplate, data)
 	if err != nil {
 		log.Err(err).Msg("Error parsing matchinfo into template")
 		return "", err
@@ -2,6 +2,8 @@ package template
 
 import (
 	"github.com/GSH-LAN/Unwindia_common/src/go/matchservice"
+	"github.com/GSH-LAN/Unwindia_dotlan_forum_manager/cmd/unwindia_dotlan_forum_manager/dotlan"
+	"strings"
 	"testing"
 )
 
@@ -45,6 +47,17 @@ Password: password
 RCON-Password: secret
 
 <a href="steam://connect/127.0.0.1:27015/password">connect 127.0.0.1:27015;password password</a>
+`
+
+	expectedTemplateText1TeamsAndServerReadyRedacted = `This match is managed by UNWINDIA
+
+Your server is ready, find the connection details below:
+
+IP-Address: 127.0.0.1:27015
+Password: ********
+RCON-Password: ********
+
+<a href="steam://connect/127.0.0.1:27015/********">connect 127.0.0.1:27015;password ********</a>
 `
 
 	templateTextBroken = ` This is a broken template {{ .UnKnownAttribute}} `
@@ -192,3 +205,366 @@ func TestParseTemplateForMatch(t *testing.T) {
 		})
 	}
 }
+
+func TestSelectTemplate(t *testing.T) {
+	templates := map[string]string{
+		"CMS_FORUM_POST.gohtml":                     "default",
+		"CMS_FORUM_POST.csgo.gohtml":                "csgo",
+		"CMS_FORUM_POST.MATCH_NEW.gohtml":           "new",
+		"CMS_FORUM_POST.csgo.MATCH_FINISHED.gohtml": "csgo-finished",
+	}
+
+	type args struct {
+		templates map[string]string
+		game      string
+		subType   string
+	}
+	tests := []struct {
+		name     string
+		args     args
+		wantName string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "ok-game_and_subtype",
+			args:     args{templates: templates, game: "csgo", subType: "UNWINDIA_MATCH_FINISHED"},
+			wantName: "CMS_FORUM_POST.csgo.MATCH_FINISHED.gohtml",
+			want:     "csgo-finished",
+		},
+		{
+			name:     "ok-fallback_subtype",
+			args:     args{templates: templates, game: "csgo", subType: "UNWINDIA_MATCH_NEW"},
+			wantName: "CMS_FORUM_POST.MATCH_NEW.gohtml",
+			want:     "new",
+		},
+		{
+			name:     "ok-fallback_game",
+			args:     args{templates: templates, game: "csgo", subType: "UNWINDIA_MATCH_READY_A"},
+			wantName: "CMS_FORUM_POST.csgo.gohtml",
+			want:     "csgo",
+		},
+		{
+			name:     "ok-fallback_default",
+			args:     args{templates: templates, game: "lol", subType: "UNWINDIA_MATCH_FINISHED"},
+			wantName: "CMS_FORUM_POST.gohtml",
+			want:     "default",
+		},
+		{
+			name:    "err-not_found",
+			args:    args{templates: map[string]string{}, game: "csgo", subType: "UNWINDIA_MATCH_NEW"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotName, got, err := SelectTemplate(tt.args.templates, ForumPostTemplate, tt.args.game, tt.args.subType)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SelectTemplate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if gotName != tt.wantName {
+				t.Errorf("SelectTemplate() gotName = %v, want %v", gotName, tt.wantName)
+			}
+			if got != tt.want {
+				t.Errorf("SelectTemplate() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTitle(t *testing.T) {
+	type args struct {
+		tpl  string
+		data *MatchContext
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "ok-team_names",
+			args: args{
+				tpl:  `{{ .Team1.Name }} vs {{ .Team2.Name }}`,
+				data: &MatchContext{MatchInfo: &matchNew},
+			},
+			want: "cool-team vs nice-teams",
+		},
+		{
+			name: "ok-collapse_whitespace",
+			args: args{
+				tpl:  "\n  {{ .Team1.Name }}\n\tvs  {{ .Team2.Name }}\n",
+				data: &MatchContext{MatchInfo: &matchNew},
+			},
+			want: "cool-team vs nice-teams",
+		},
+		{
+			name: "ok-dotlan_records",
+			args: args{
+				tpl:  `{{ .Team1.Name }} vs {{ .Team2.Name }} (Round {{ .Dotlan.Contest.Round }})`,
+				data: &MatchContext{MatchInfo: &matchNew, Dotlan: &dotlan.MatchData{Contest: &dotlan.Contest{Round: 2}}},
+			},
+			want: "cool-team vs nice-teams (Round 2)",
+		},
+		{
+			name: "ok-cut_long_title",
+			args: args{
+				tpl:  strings.Repeat("a", 300),
+				data: &MatchContext{MatchInfo: &matchNew},
+			},
+			want: strings.Repeat("a", 255),
+		},
+		{
+			name: "err-empty_title",
+			args: args{
+				tpl:  `{{ .Map }}`,
+				data: &MatchContext{MatchInfo: &matchNew},
+			},
+			wantErr: true,
+		},
+		{
+			name: "err-template_text_broken",
+			args: args{
+				tpl:  templateTextBroken,
+				data: &MatchContext{MatchInfo: &matchNew},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTitle(tt.args.tpl, tt.args.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseTitle() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseTitle() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTMLToBBCode(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "ok-plain_text",
+			text: "This match is managed by UNWINDIA\n\nAs soon both teams are ready",
+			want: "This match is managed by UNWINDIA\n\nAs soon both teams are ready",
+		},
+		{
+			name: "ok-formatting",
+			text: "<b>IP</b>: <strong>1.2.3.4</strong> <i>pw</i> <em>x</em> <u>u</u> <del>old</del>",
+			want: "[b]IP[/b]: [b]1.2.3.4[/b] [i]pw[/i] [i]x[/i] [u]u[/u] [s]old[/s]",
+		},
+		{
+			name: "ok-link",
+			text: `<a href="steam://connect/127.0.0.1:27015/password">connect 127.0.0.1:27015;password password</a>`,
+			want: "[url=steam://connect/127.0.0.1:27015/password]connect 127.0.0.1:27015;password password[/url]",
+		},
+		{
+			name: "ok-anchor_without_href",
+			text: `<a name="top">top</a>`,
+			want: "top",
+		},
+		{
+			name: "ok-line_breaks_and_paragraphs",
+			text: "<p>first</p><p>second<br>third<br/>fourth</p>",
+			want: "first\nsecond\nthird\nfourth\n",
+		},
+		{
+			name: "ok-list_and_image",
+			text: `<ul><li>one</li><li>two</li></ul><ol><li>1</li></ol><img src="https://example.com/logo.png">`,
+			want: "[list][*]one[*]two[/list][list=1][*]1[/list][img]https://example.com/logo.png[/img]",
+		},
+		{
+			name: "ok-entities_and_unknown_tags",
+			text: `<span class="x">cool &amp; nice</span> &lt;3 <h2>Server</h2><pre>rcon</pre>`,
+			want: "cool & nice <3 [b]Server[/b][code]rcon[/code]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTMLToBBCode(tt.text); got != tt.want {
+				t.Errorf("HTMLToBBCode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTemplate(t *testing.T) {
+	dotlanData := &dotlan.MatchData{
+		Contest: &dotlan.Contest{Round: 2},
+		Team1:   &dotlan.Team{Tnid: 11, Members: []dotlan.TeamMember{{Nick: "alice"}, {Nick: "bob"}}},
+	}
+
+	tests := []struct {
+		name    string
+		tpl     string
+		data    *MatchContext
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "ok-matchinfo_and_dotlan",
+			tpl:  `{{ .Team1.Name }} (round {{ .Dotlan.Contest.Round }}):{{ range .Dotlan.Team1.Members }} {{ .Nick }}{{ end }}`,
+			data: &MatchContext{MatchInfo: &matchNew, Dotlan: dotlanData},
+			want: "cool-team (round 2): alice bob",
+		},
+		{
+			name: "ok-without_dotlan",
+			tpl:  `{{ .Team1.Name }}{{ with .Dotlan }} round {{ .Contest.Round }}{{ end }}`,
+			data: &MatchContext{MatchInfo: &matchNew},
+			want: "cool-team",
+		},
+		{
+			name:    "err-empty_matchinfo",
+			tpl:     `{{ .Team1.Name }}`,
+			data:    &MatchContext{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTemplate(tt.tpl, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseTemplate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseTemplate() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRedactionPolicy(t *testing.T) {
+	tests := []struct {
+		name            string
+		sensitiveFields []string
+		wantErr         bool
+	}{
+		{
+			name:            "ok-default",
+			sensitiveFields: DefaultSensitiveFields,
+		},
+		{
+			name:            "ok-empty_entries",
+			sensitiveFields: []string{" ServerPassword ", ""},
+		},
+		{
+			name:            "err-unknown_field",
+			sensitiveFields: []string{"RconPassword"},
+			wantErr:         true,
+		},
+		{
+			name:            "err-no_string_field",
+			sensitiveFields: []string{"Team1"},
+			wantErr:         true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRedactionPolicy(tt.sensitiveFields, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewRedactionPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRedactionPolicy_MatchInfoForForum(t *testing.T) {
+	policy, err := NewRedactionPolicy([]string{"ServerPassword", "ServerPasswordMgmt"}, []int{42})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		matchInfo *matchservice.MatchInfo
+		forumId   int
+		want      string
+	}{
+		{
+			name:      "ok-private_forum",
+			matchInfo: &matchTeamsAndServerReady,
+			forumId:   42,
+			want:      expectedTemplateText1TeamsAndServerReady,
+		},
+		{
+			name:      "ok-public_forum",
+			matchInfo: &matchTeamsAndServerReady,
+			forumId:   9,
+			want:      expectedTemplateText1TeamsAndServerReadyRedacted,
+		},
+		{
+			name:      "ok-empty_values_not_masked",
+			matchInfo: &matchNew,
+			forumId:   9,
+			want:      expectedTemplateText1NewMatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTemplateForMatch(templateText1, policy.MatchInfoForForum(tt.matchInfo, tt.forumId))
+			if err != nil {
+				t.Fatalf("ParseTemplateForMatch() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTemplateForMatch() got = %v, want %v", got, tt.want)
+			}
+			if matchTeamsAndServerReady.ServerPasswordMgmt != "secret" {
+				t.Errorf("MatchInfoForForum() modified the original MatchInfo")
+			}
+		})
+	}
+}
+
+func TestRedactionPolicy_Scrub(t *testing.T) {
+	policy, err := NewRedactionPolicy(DefaultSensitiveFields, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		policy    *RedactionPolicy
+		matchInfo *matchservice.MatchInfo
+		text      string
+		want      string
+	}{
+		{
+			name:      "ok-secret_scrubbed",
+			policy:    policy,
+			matchInfo: &matchTeamsAndServerReady,
+			text:      "rcon_password secret; password password",
+			want:      "rcon_password " + RedactedValue + "; password password",
+		},
+		{
+			name:      "ok-empty_secret",
+			policy:    policy,
+			matchInfo: &matchNew,
+			text:      "nothing to hide",
+			want:      "nothing to hide",
+		},
+		{
+			name:      "ok-nil_policy",
+			matchInfo: &matchTeamsAndServerReady,
+			text:      "secret",
+			want:      "secret",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Scrub(tt.matchInfo, tt.text); got != tt.want {
+				t.Errorf("Scrub() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package template
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	// maxTitleLength is the maximum length of a forum thread title in characters
+	maxTitleLength = 255
+)
+
+var (
+	// ErrEmptyTitle is returned if a title template renders to an empty title
+	ErrEmptyTitle = errors.New("rendered title is empty")
+)
+
+// ParseTitle renders the title template for the match context. Since thread titles are single lines of plain text, all
+// whitespace including line breaks is collapsed into single spaces and the title is cut at maxTitleLength.
+func ParseTitle(tpl string, data *MatchContext) (string, error) {
+	parsed, err := ParseTemplate(tpl, data)
+	if err != nil {
+		return "", err
+	}
+
+	title := strings.Join(strings.Fields(parsed), " ")
+	if title == "" {
+		return "", ErrEmptyTitle
+	}
+
+	if runes := []rune(title); len(runes) > maxTitleLength {
+		title = strings.TrimSpace(string(runes[:maxTitleLength]))
+	}
+
+	return title, nil
+}